@@ -1,71 +1,412 @@
-package main
-
-import (
-	"fmt"
-	"io"
-	"os"
-
-	v6 "github.com/ddvk/reader/v6"
-	log "github.com/sirupsen/logrus"
-	prefixed "github.com/x-cray/logrus-prefixed-formatter"
-)
-
-func parseSceneFile(file io.ReadSeekCloser) (err error) {
-	headerLength := 0x2b
-	buffer := make([]byte, headerLength)
-	_, err = io.ReadFull(file, buffer)
-	if err != nil {
-		return err
-	}
-	//todo: checkversion blah blah
-
-	sceneParser := v6.SceneReader{}
-	scene, err := sceneParser.ExtractScene(file)
-	if err != nil {
-		return
-	}
-	log.Info("parsed: ", scene)
-
-	fmt.Printf("Number of Layer: %d\n", len(scene.Layers))
-	for i, layer := range scene.Layers {
-		fmt.Printf("Layer: %d num lines:%d\n", i, len(layer.Lines))
-		for j, line := range layer.Lines {
-			fmt.Printf("\tLine: %d points: %d\n", j, len(line.Line.Value.Points))
-			for _, point := range line.Line.Value.Points {
-				fmt.Printf("\t\t\tX: %f Y: %f speed: %d width: %d\n", point.X, point.Y, point.Speed, point.Width)
-			}
-		}
-	}
-	return
-}
-
-func _main() error {
-	if len(os.Args) < 2 {
-		log.Print("missing file")
-		return nil
-	}
-	filename := os.Args[1]
-	file, err := os.Open(filename)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	return parseSceneFile(file)
-}
-
-func main() {
-	prefixed := &prefixed.TextFormatter{
-		TimestampFormat: "2006-01-02 15:04:05",
-		FullTimestamp:   true,
-		ForceFormatting: true,
-		ForceColors:     true,
-	}
-	log.SetFormatter(prefixed)
-	log.SetOutput(os.Stdout)
-	log.SetLevel(log.DebugLevel)
-	err := _main()
-	if err != nil {
-		log.Fatal(err)
-	}
-}
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ddvk/reader/render"
+	v6 "github.com/ddvk/reader/v6"
+	log "github.com/sirupsen/logrus"
+	prefixed "github.com/x-cray/logrus-prefixed-formatter"
+)
+
+func parseSceneFile(file io.ReadSeekCloser) (err error) {
+	headerLength := 0x2b
+	buffer := make([]byte, headerLength)
+	_, err = io.ReadFull(file, buffer)
+	if err != nil {
+		return err
+	}
+	//todo: checkversion blah blah
+
+	sceneParser := v6.SceneReader{}
+	scene, err := sceneParser.ExtractScene(file)
+	if err != nil {
+		return
+	}
+	log.Info("parsed: ", scene)
+
+	fmt.Printf("Number of Layer: %d\n", len(scene.Layers))
+	for i, layer := range scene.Layers {
+		fmt.Printf("Layer: %d num lines:%d\n", i, len(layer.Lines))
+		for j, line := range layer.Lines {
+			fmt.Printf("\tLine: %d points: %d\n", j, len(line.Line.Value.Points))
+			for _, point := range line.Line.Value.Points {
+				fmt.Printf("\t\t\tX: %f Y: %f speed: %d width: %d\n", point.X, point.Y, point.Speed, point.Width)
+			}
+		}
+	}
+	return
+}
+
+type renderer func(io.Writer, *v6.Scene, *render.Options) error
+
+// renderFile renders the page, the format is taken from the file extension.
+// Split pages are written to numbered files unless the output is a pdf.
+func renderFile(file io.Reader, output string, opts *render.Options, replay bool) (err error) {
+	var renderer renderer
+	ext := filepath.Ext(output)
+	switch strings.ToLower(ext) {
+	case ".svg":
+		renderer = render.SVG
+		if replay {
+			renderer = func(w io.Writer, scene *v6.Scene, opts *render.Options) error {
+				return render.AnimatedSVG(w, scene, opts, nil)
+			}
+		}
+	case ".gif":
+		renderer = func(w io.Writer, scene *v6.Scene, opts *render.Options) error {
+			return render.GIF(w, scene, opts, nil)
+		}
+	case ".png":
+		renderer = render.PNG
+	case ".jpg", ".jpeg":
+		renderer = render.JPEG
+	case ".pdf":
+		renderer = render.PDF
+	default:
+		return fmt.Errorf("unknown output format: %s", output)
+	}
+
+	scene, err := v6.ReadScene(file)
+	if err != nil {
+		return
+	}
+	if !opts.Split || strings.EqualFold(ext, ".pdf") {
+		return writeFile(output, renderer, &scene, opts)
+	}
+	frame := render.PageFrame(&scene, opts)
+	if opts.Frame != nil {
+		frame = *opts.Frame
+	}
+	for i, page := range render.SplitPages(frame) {
+		pageOpts := *opts
+		pageOpts.Frame = &page
+		pageOpts.Split = false
+		name := fmt.Sprintf("%s-%d%s", strings.TrimSuffix(output, ext), i+1, ext)
+		err = writeFile(name, renderer, &scene, &pageOpts)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func writeFile(output string, renderer renderer, scene *v6.Scene, opts *render.Options) error {
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	return renderer(out, scene, opts)
+}
+
+// previewFile draws the page in the terminal, as wide as the terminal
+func previewFile(file io.Reader, preview string, opts *render.Options) error {
+	mode, err := previewMode(preview)
+	if err != nil {
+		return err
+	}
+	scene, err := v6.ReadScene(file)
+	if err != nil {
+		return err
+	}
+	return render.Terminal(os.Stdout, &scene, opts, mode, terminalColumns())
+}
+
+// tileFile renders the page as a tile pyramid, a deep zoom image or tiles
+// in the xyz layout of web maps
+func tileFile(file io.Reader, output, kind string, size int, opts *render.Options) error {
+	scene, err := v6.ReadScene(file)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case "dzi":
+		tiles := *render.DefaultDeepZoom
+		if size > 0 {
+			tiles.Size = size
+		}
+		name := strings.TrimSuffix(filepath.Base(output), filepath.Ext(output))
+		return render.DeepZoom(filepath.Dir(output), name, &scene, opts, &tiles)
+	case "xyz":
+		tiles := *render.DefaultXYZ
+		if size > 0 {
+			tiles.Size = size
+		}
+		return render.XYZ(output, &scene, opts, &tiles)
+	}
+	return fmt.Errorf("unknown tiles: %s", kind)
+}
+
+// thumbnailFiles writes the thumbnail of a page to the output and refreshes
+// the thumbnails of document directories
+func thumbnailFiles(files []string, output, dir string, opts *render.Options) error {
+	if dir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return err
+		}
+		dir = filepath.Join(userCache, "reader", "thumbnails")
+	}
+	cache := &render.ThumbnailCache{Dir: dir, Options: opts}
+	infos := make([]os.FileInfo, len(files))
+	pages := 0
+	for i, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		infos[i] = info
+		if !info.IsDir() {
+			pages++
+		}
+	}
+	if pages > 1 {
+		return fmt.Errorf("-o holds the thumbnail of one page, %d pages given", pages)
+	}
+	for i, name := range files {
+		if infos[i].IsDir() {
+			updated, err := cache.RefreshDocument(name)
+			if err != nil {
+				return err
+			}
+			for _, file := range updated {
+				log.Info("thumbnail: ", file)
+			}
+			continue
+		}
+		if output == "" {
+			return fmt.Errorf("a thumbnail of a page needs an output file")
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		thumbnail, err := cache.Get(data)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(output, thumbnail, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overlayFile draws the pages on the source pdf, the files are the pages
+// in order, "-" leaves a page as it is
+func overlayFile(source string, files []string, output string, opts *render.Options) (err error) {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return
+	}
+	scenes := make([]*v6.Scene, len(files))
+	for i, name := range files {
+		if name == "-" {
+			continue
+		}
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		scene, err := v6.ReadScene(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		scenes[i] = &scene
+	}
+	out, err := os.Create(output)
+	if err != nil {
+		return
+	}
+	defer out.Close()
+	return render.Overlay(out, data, scenes, opts)
+}
+
+// parseFilter reads the comma separated lists of layers, tools and colors
+func parseFilter(layers, tools, colors string) (*render.Filter, error) {
+	filter := &render.Filter{}
+	if layers != "" {
+		filter.Layers = strings.Split(layers, ",")
+	}
+	if tools != "" {
+		for _, name := range strings.Split(tools, ",") {
+			tool, ok := v6.ParseTool(strings.TrimSpace(name))
+			if !ok {
+				return nil, fmt.Errorf("unknown tool: %s", name)
+			}
+			filter.Tools = append(filter.Tools, tool)
+		}
+	}
+	if colors != "" {
+		for _, name := range strings.Split(colors, ",") {
+			color, ok := v6.ParsePenColor(strings.TrimSpace(name))
+			if !ok {
+				return nil, fmt.Errorf("unknown color: %s", name)
+			}
+			filter.Colors = append(filter.Colors, color)
+		}
+	}
+	return filter, nil
+}
+
+// parseRect reads x,y,width,height
+func parseRect(s string) (r v6.Rect, err error) {
+	var x, y, width, height float32
+	if _, err = fmt.Sscanf(s, "%f,%f,%f,%f", &x, &y, &width, &height); err != nil {
+		return r, fmt.Errorf("rect %q: %w", s, err)
+	}
+	if width <= 0 || height <= 0 {
+		return r, fmt.Errorf("rect %q: empty", s)
+	}
+	return v6.NewRect(x, y, width, height), nil
+}
+
+// loadPalette returns a built in palette or reads a palette file
+func loadPalette(name string) (*render.Palette, error) {
+	if palette, ok := render.Palettes[name]; ok {
+		return palette, nil
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return render.ReadPalette(file)
+}
+
+func _main() error {
+	output := flag.String("o", "", "render the page to this file (.svg, .png, .jpg, .pdf, .gif)")
+	replay := flag.Bool("replay", false, "svg output draws the lines in the order they were written, gif always does")
+	dpi := flag.Float64("dpi", render.DPI, "resolution of png and jpg output")
+	display := flag.String("display", "", "show png and jpg output as the e-paper panel does: rm1, rm2, pro")
+	paletteName := flag.String("palette", "default", "colors: default, dark, print or a json file")
+	templateName := flag.String("template", "", "background template: "+strings.Join(render.TemplateNames(), ", ")+" or a file in -templates")
+	templateDir := flag.String("templates", "", "directory with template files, name.svg and name.png")
+	debug := flag.Bool("debug", false, "draw the bounding boxes, ids and anchors, the lines in the colors of their authors")
+	split := flag.Bool("split", false, "split tall pages into pages of the device size, numbered files for svg, png and jpg")
+	page := flag.Bool("page", false, "draw only the page of the device, not the content past it")
+	texture := flag.Bool("texture", false, "draw pencils and the paintbrush with the grain of the paper in png and jpg output")
+	seed := flag.Int64("seed", 0, "seed of the grain, the same seed draws the same grain")
+	crop := flag.Bool("crop", false, "draw only the content, with -margin around it")
+	margin := flag.Float64("margin", 0, "space around the content when cropping, in pixels of the device")
+	rect := flag.String("rect", "", "draw only this area of the page: x,y,width,height in pixels of the device, x = 0 is the center")
+	source := flag.String("pdf", "", "draw the pages on this pdf, the files are its pages in order, - skips a page")
+	layers := flag.String("layers", "", "draw only these layers, comma separated names or indexes from 0")
+	hidden := flag.Bool("hidden", false, "draw the layers that are hidden on the device")
+	tools := flag.String("tools", "", "draw only the lines of these tools, comma separated: pencil, fineliner, ...")
+	colors := flag.String("colors", "", "draw only the lines and highlights of these colors, comma separated: black, red, ...")
+	noHighlights := flag.Bool("nohighlights", false, "leave out the highlights of the text")
+	noText := flag.Bool("notext", false, "leave out the typed text")
+	showErased := flag.Bool("erased", false, "draw the ink under the eraser strokes")
+	tiles := flag.String("tiles", "", "render a tile pyramid: dzi writes -o name.dzi and name_files, xyz writes the tiles into the directory -o")
+	tileSize := flag.Int("tilesize", 0, "size of the tiles in pixels, 254 for dzi and 256 for xyz")
+	thumbnail := flag.Bool("thumbnail", false, "write a thumbnail of the page to -o, the files can be document directories, their missing and stale thumbnails are written to dir.thumbnails")
+	cacheDir := flag.String("cache", "", "directory of the thumbnail cache, the user cache directory when not set")
+	preview := flag.String("preview", "", "draw the page in the terminal: auto, braille, blocks or sixel")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		log.Print("missing file")
+		return nil
+	}
+	filter, err := parseFilter(*layers, *tools, *colors)
+	if err != nil {
+		return err
+	}
+	filter.NoHighlights = *noHighlights
+	filter.NoText = *noText
+	filter.ShowErased = *showErased
+	if *preview != "" {
+		// the log would end up in the middle of the page
+		log.SetOutput(os.Stderr)
+		log.SetLevel(log.WarnLevel)
+	}
+	if *thumbnail {
+		palette, err := loadPalette(*paletteName)
+		if err != nil {
+			return err
+		}
+		return thumbnailFiles(flag.Args(), *output, *cacheDir, &render.Options{Palette: palette, HiddenLayers: *hidden, Filter: filter})
+	}
+	if *source != "" {
+		if *output == "" {
+			return fmt.Errorf("-pdf needs an output file")
+		}
+		palette, err := loadPalette(*paletteName)
+		if err != nil {
+			return err
+		}
+		return overlayFile(*source, flag.Args(), *output, &render.Options{Palette: palette, HiddenLayers: *hidden, Filter: filter})
+	}
+	filename := flag.Arg(0)
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if *output != "" || *preview != "" {
+		palette, err := loadPalette(*paletteName)
+		if err != nil {
+			return err
+		}
+		opts := &render.Options{DPI: *dpi, Palette: palette, Split: *split, Debug: *debug, HiddenLayers: *hidden, Filter: filter}
+		if *display != "" {
+			opts.Display = render.Displays[*display]
+			if opts.Display == nil {
+				return fmt.Errorf("unknown display: %s", *display)
+			}
+		}
+		if *page {
+			frame := render.Page
+			opts.Frame = &frame
+		}
+		if *rect != "" {
+			frame, err := parseRect(*rect)
+			if err != nil {
+				return err
+			}
+			opts.Frame = &frame
+		}
+		opts.Texture = *texture
+		opts.Seed = *seed
+		opts.Crop = *crop
+		opts.Margin = *margin
+		if *templateName != "" {
+			opts.Template, err = render.LoadTemplate(*templateName, *templateDir)
+			if err != nil {
+				return err
+			}
+		}
+		if *preview != "" {
+			return previewFile(file, *preview, opts)
+		}
+		if *tiles != "" {
+			return tileFile(file, *output, *tiles, *tileSize, opts)
+		}
+		return renderFile(file, *output, opts, *replay)
+	}
+	return parseSceneFile(file)
+}
+
+func main() {
+	prefixed := &prefixed.TextFormatter{
+		TimestampFormat: "2006-01-02 15:04:05",
+		FullTimestamp:   true,
+		ForceFormatting: true,
+		ForceColors:     true,
+	}
+	log.SetFormatter(prefixed)
+	log.SetOutput(os.Stdout)
+	log.SetLevel(log.DebugLevel)
+	err := _main()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+// queryTerminal is not supported, the terminal is not asked
+func queryTerminal(query string, final byte, timeout time.Duration) string {
+	return ""
+}
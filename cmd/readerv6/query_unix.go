@@ -0,0 +1,50 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// queryTerminal writes the query and reads the reply up to the final
+// byte. Stdin is read without blocking until the time is up, so a late
+// reply is not taken by a read that outlives the query.
+func queryTerminal(query string, final byte, timeout time.Duration) string {
+	in := int(os.Stdin.Fd())
+	if !term.IsTerminal(in) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return ""
+	}
+	state, err := term.MakeRaw(in)
+	if err != nil {
+		return ""
+	}
+	defer term.Restore(in, state)
+	if err := syscall.SetNonblock(in, true); err != nil {
+		return ""
+	}
+	defer syscall.SetNonblock(in, false)
+	os.Stdout.WriteString(query)
+
+	var reply []byte
+	b := make([]byte, 1)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		n, err := syscall.Read(in, b)
+		switch {
+		case n == 1:
+			reply = append(reply, b[0])
+			if b[0] == final {
+				return string(reply)
+			}
+		case err == syscall.EAGAIN || err == syscall.EINTR:
+			time.Sleep(5 * time.Millisecond)
+		default:
+			return string(reply)
+		}
+	}
+	return string(reply)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ddvk/reader/render"
+	"golang.org/x/term"
+)
+
+// terminalColumns is the width of the terminal, $COLUMNS or 80 when the
+// output is not a terminal
+func terminalColumns() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return 80
+}
+
+// previewMode picks how the page is drawn, auto uses sixel when the
+// terminal has it and colors when the terminal says it has 24 bit colors
+func previewMode(name string) (render.TerminalMode, error) {
+	switch name {
+	case "braille":
+		return render.Braille, nil
+	case "blocks":
+		return render.HalfBlocks, nil
+	case "sixel":
+		return render.Sixel, nil
+	case "auto":
+		if sixelSupported() {
+			return render.Sixel, nil
+		}
+		switch os.Getenv("COLORTERM") {
+		case "truecolor", "24bit":
+			return render.HalfBlocks, nil
+		}
+		return render.Braille, nil
+	}
+	return 0, fmt.Errorf("unknown preview: %s", name)
+}
+
+// sixelSupported asks the terminal for its device attributes, 4 in the
+// reply is sixel graphics. Terminals that don't answer have no sixel.
+func sixelSupported() bool {
+	answer := queryTerminal("\x1b[c", 'c', 200*time.Millisecond)
+	if !strings.HasSuffix(answer, "c") {
+		return false
+	}
+	// ESC [ ? class ; attributes c
+	answer = strings.TrimSuffix(strings.TrimPrefix(answer, "\x1b[?"), "c")
+	attributes := strings.Split(answer, ";")
+	for _, attribute := range attributes[1:] {
+		if attribute == "4" {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,8 @@ require (
 	github.com/google/uuid v1.3.0
 	github.com/sirupsen/logrus v1.9.0
 	github.com/x-cray/logrus-prefixed-formatter v0.5.2
+	golang.org/x/image v0.0.0-20200119044424-58c23975cae1
+	golang.org/x/term v0.1.0
 )
 
 require (
@@ -26,9 +28,7 @@ require (
 	github.com/stretchr/testify v1.7.0 // indirect
 	github.com/unidoc/unipdf/v3 v3.6.1 // indirect
 	golang.org/x/crypto v0.1.0 // indirect
-	golang.org/x/image v0.0.0-20200119044424-58c23975cae1 // indirect
 	golang.org/x/sys v0.2.0 // indirect
-	golang.org/x/term v0.1.0 // indirect
 	golang.org/x/text v0.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
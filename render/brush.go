@@ -0,0 +1,142 @@
+package render
+
+import (
+	"math"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// brush models how a tool puts ink on the page, the width and the opacity
+// follow the pressure, speed and direction of the pen
+type brush struct {
+	// Width of the stroke at the point in pixels
+	Width func(line *v6.Line, p *v6.PenPoint) float64
+	// Opacity of the ink at the point, 0 to 1
+	Opacity func(p *v6.PenPoint) float64
+}
+
+// opacity levels, points with the same level are drawn as one path
+const opacityLevels = 20
+
+var brushes = map[v6.Tool]brush{
+	v6.ToolBallpoint: {
+		// the ball rolls out more ink when it is pressed, less when it is fast
+		Width: func(line *v6.Line, p *v6.PenPoint) float64 {
+			return toolSize(line, p) * clamp((0.5+0.7*pressure(p))*(1-0.15*math.Min(speed(p)/50, 1)), 0.4, 1.2)
+		},
+		// light and fast strokes leave less ink
+		Opacity: func(p *v6.PenPoint) float64 {
+			return clamp(0.5+1.2*pressure(p)-0.1*speed(p)/35, 0.3, 1)
+		},
+	},
+	v6.ToolFineliner: {
+		Width:   toolSize,
+		Opacity: solid,
+	},
+	v6.ToolMarker: {
+		// the chisel tip is wider across its edge
+		Width: func(line *v6.Line, p *v6.PenPoint) float64 {
+			return toolSize(line, p) * (0.8 + 0.3*math.Abs(math.Sin(direction(p)-math.Pi/4)))
+		},
+		Opacity: solid,
+	},
+	v6.ToolPencil: {
+		// the lead spreads when it is pressed, fast strokes touch less of it
+		Width: func(line *v6.Line, p *v6.PenPoint) float64 {
+			return toolSize(line, p) * clamp((0.6+0.7*pressure(p))*(1-0.2*math.Min(speed(p)/40, 1)), 0.4, 1.3)
+		},
+		Opacity: func(p *v6.PenPoint) float64 {
+			return clamp(pressure(p)-0.1*speed(p)/35, 0.15, 1)
+		},
+	},
+	v6.ToolMechanicalPencil: {
+		// the thin lead hardly spreads
+		Width: func(line *v6.Line, p *v6.PenPoint) float64 {
+			return toolSize(line, p) * (0.9 + 0.2*pressure(p))
+		},
+		Opacity: func(p *v6.PenPoint) float64 {
+			return clamp(0.5+0.4*pressure(p), 0.5, 0.9)
+		},
+	},
+	v6.ToolPaintbrush: {
+		// the bristles spread to four times the size, less when the brush
+		// is fast
+		Width: func(line *v6.Line, p *v6.PenPoint) float64 {
+			return toolSize(line, p) * (1 + 3*math.Pow(pressure(p), 1.5)) * (1 - 0.3*math.Min(speed(p)/50, 1))
+		},
+		Opacity: func(p *v6.PenPoint) float64 {
+			return clamp(1.5*(math.Pow(pressure(p), 1.5)-0.2*speed(p)/50), 0.2, 1)
+		},
+	},
+	v6.ToolCalligraphy: {
+		// the nib follows the pressure and the direction, see nibAt
+		Width:   toolSize,
+		Opacity: solid,
+	},
+	v6.ToolHighlighter: {
+		Width:   toolSize,
+		Opacity: constant(0.4),
+	},
+	v6.ToolShader: {
+		Width:   toolSize,
+		Opacity: constant(0.25),
+	},
+}
+
+// brushFor returns the brush of the tool, unknown tools draw like a
+// fineliner. Erasers have no brush.
+func brushFor(tool v6.Tool) (brush, bool) {
+	if tool.IsEraser() {
+		return brush{}, false
+	}
+	if b, ok := brushes[tool.Base()]; ok {
+		return b, true
+	}
+	return brushes[v6.ToolFineliner], true
+}
+
+// toolSize is the size of the tool at the point in pixels, the width the
+// device stores in the point. Older lines without it use the thickness of
+// the line, the device stores twice of it as the width of a fineliner.
+// v6.Transform scales the same one.
+func toolSize(line *v6.Line, p *v6.PenPoint) float64 {
+	if p.Width == 0 {
+		return 2 * line.ThicknessScale
+	}
+	return float64(p.Width) / 4
+}
+
+func solid(p *v6.PenPoint) float64 {
+	return 1
+}
+
+func constant(opacity float64) func(p *v6.PenPoint) float64 {
+	return func(p *v6.PenPoint) float64 {
+		return opacity
+	}
+}
+
+// pressure of the pen, 0 to 1
+func pressure(p *v6.PenPoint) float64 {
+	return float64(p.Pressure) / 0xff
+}
+
+// speed in the unit of the first point version
+func speed(p *v6.PenPoint) float64 {
+	return float64(p.Speed) / 4
+}
+
+// direction the pen moved in radians, a full turn is 255 as in
+// ExtractPointV1
+func direction(p *v6.PenPoint) float64 {
+	return float64(p.Direction) * 2 * math.Pi / 255
+}
+
+func clamp(f, min, max float64) float64 {
+	return math.Min(math.Max(f, min), max)
+}
+
+// quantize rounds the opacity to one of the levels
+func quantize(opacity float64) float64 {
+	return math.Round(opacity*opacityLevels) / opacityLevels
+}
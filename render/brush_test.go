@@ -0,0 +1,77 @@
+package render
+
+import (
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+func TestBrushes(t *testing.T) {
+	tests := []struct {
+		tool     v6.Tool
+		pressure bool
+	}{
+		{v6.ToolBallpoint, true},
+		{v6.ToolFineliner, false},
+		{v6.ToolMarker, false},
+		{v6.ToolPencil, true},
+		{v6.ToolMechanicalPencil, true},
+		{v6.ToolPaintbrush, true},
+		// the nib follows the pressure
+		{v6.ToolCalligraphy, false},
+		{v6.ToolHighlighter, false},
+		{v6.ToolShader, false},
+	}
+	for _, test := range tests {
+		b, ok := brushFor(test.tool)
+		if !ok {
+			t.Errorf("%v has no brush", test.tool)
+			continue
+		}
+		line := &testLine(test.tool).Line.Value
+		light := &v6.PenPoint{Pressure: 50, Width: 16}
+		hard := &v6.PenPoint{Pressure: 250, Width: 16}
+		if w := b.Width(line, light); w <= 0 {
+			t.Errorf("%v: width %v", test.tool, w)
+		}
+		if grows := b.Width(line, hard) > b.Width(line, light); grows != test.pressure {
+			t.Errorf("%v: width grows with the pressure %t, want %t", test.tool, grows, test.pressure)
+		}
+	}
+	for _, tool := range []v6.Tool{v6.ToolEraser, v6.ToolEraseArea} {
+		if _, ok := brushFor(tool); ok {
+			t.Errorf("%v has a brush", tool)
+		}
+		if s := newStroke(testLine(tool, 0, 0, 10, 10), DefaultPalette); s != nil {
+			t.Errorf("%v is drawn", tool)
+		}
+	}
+}
+
+func TestToolSize(t *testing.T) {
+	line := &v6.Line{ThicknessScale: 2}
+	if size := toolSize(line, &v6.PenPoint{Width: 24}); size != 6 {
+		t.Errorf("size %v of the point width, want 6", size)
+	}
+	if size := toolSize(line, &v6.PenPoint{}); size != 4 {
+		t.Errorf("size %v of the thickness, want 4", size)
+	}
+}
+
+// a scaled line is drawn with the scaled width, whether the width is in the
+// points or only in the thickness of the line
+func TestScaledWidth(t *testing.T) {
+	frame := &v6.Rect{MinX: 0, MinY: 0, MaxX: 200, MaxY: 200}
+	for _, width := range []uint16{16, 0} {
+		line := testLine(v6.ToolFineliner, 10, 100, 190, 100)
+		for _, p := range line.Line.Value.Points {
+			p.Width = width
+		}
+		before := inkAcross(Raster(testScene(line), &Options{Frame: frame, DPI: DPI}), false)
+		v6.Scale(3, 3).Around(100, 100).TransformLines([]*v6.LineItem{line})
+		after := inkAcross(Raster(testScene(line), &Options{Frame: frame, DPI: DPI}), false)
+		if after < 2*before || after > 4*before {
+			t.Errorf("width %d: %d pixels of ink scaled three times, was %d", width, after, before)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// nibAngle is the angle of the edge of the calligraphy nib on the page, the
+// stroke is widest when the pen moves across the edge and a hairline along
+// it
+const nibAngle = 0
+
+// nibThickness is the thickness of the nib for its length, the hairline
+const nibThickness = 1.0 / 3
+
+// nibMinPressure is the part of the nib that touches the page without
+// pressure, all of it touches at full pressure
+const nibMinPressure = 0.4
+
+// isCalligraphy reports whether the stroke is drawn with a nib
+func (s *stroke) isCalligraphy() bool {
+	return s.Item != nil && s.Item.Line.Value.Tool.Base() == v6.ToolCalligraphy
+}
+
+// nib is the flat nib at a point: the half of its edge and the half of its
+// thickness as vectors from the point
+type nib struct {
+	X, Y, EdgeX, EdgeY, ThickX, ThickY float64
+}
+
+// nibAt is the nib at the point, the length of the edge is the width of the
+// point and follows the pressure, the thickness does not
+func nibAt(p strokePoint) nib {
+	sin, cos := math.Sincos(nibAngle)
+	edge := p.Width * (nibMinPressure + (1-nibMinPressure)*p.Pressure) / 2
+	thick := math.Min(p.Width*nibThickness/2, edge)
+	return nib{
+		X: p.X, Y: p.Y,
+		EdgeX: cos * edge, EdgeY: sin * edge,
+		ThickX: -sin * thick, ThickY: cos * thick,
+	}
+}
+
+// leading is the corner of the nib that draws the left side of the line
+// when the pen moves in the direction, the opposite corner draws the right
+// side
+func (n nib) leading(direction float64) (float64, float64) {
+	sin, cos := math.Sincos(direction)
+	side := func(x, y float64) float64 {
+		// the left of the direction, the sign of the cross product
+		if cos*y-sin*x < 0 {
+			return -1
+		}
+		return 1
+	}
+	e, t := side(n.EdgeX, n.EdgeY), side(n.ThickX, n.ThickY)
+	return e*n.EdgeX + t*n.ThickX, e*n.EdgeY + t*n.ThickY
+}
+
+// corners of the nib, the whole nib as it touches the page
+func (n nib) corners() ([]float64, []float64) {
+	xs, ys := make([]float64, 4), make([]float64, 4)
+	for i, s := range [4][2]float64{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+		xs[i] = n.X + s[0]*n.EdgeX + s[1]*n.ThickX
+		ys[i] = n.Y + s[0]*n.EdgeY + s[1]*n.ThickY
+	}
+	return xs, ys
+}
+
+// nibPolygons sweeps the flat nib along the points and calls add with the
+// outlines that cover the line: the nib at every point and between two
+// points the area the corners of the nib that lead in the direction of the
+// pen pass over. All outlines turn the same way so they add up with the
+// nonzero rule.
+func nibPolygons(points []strokePoint, add func(xs, ys []float64)) {
+	var prev nib
+	var prevX, prevY float64
+	for i, p := range points {
+		n := nibAt(p)
+		add(convexHull(n.corners()))
+		x, y := n.leading(p.Direction)
+		if i > 0 {
+			add(convexHull(
+				[]float64{prev.X + prevX, n.X + x, n.X - x, prev.X - prevX},
+				[]float64{prev.Y + prevY, n.Y + y, n.Y - y, prev.Y - prevY}))
+		}
+		prev, prevX, prevY = n, x, y
+	}
+}
+
+// convexHull returns the hull of the points counterclockwise in page
+// coordinates, the points are not changed
+func convexHull(xs, ys []float64) ([]float64, []float64) {
+	order := make([]int, len(xs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		return xs[i] < xs[j] || xs[i] == xs[j] && ys[i] < ys[j]
+	})
+	cross := func(o, a, b int) float64 {
+		return (xs[a]-xs[o])*(ys[b]-ys[o]) - (ys[a]-ys[o])*(xs[b]-xs[o])
+	}
+	hull := make([]int, 0, 2*len(order))
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, i := range order {
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], i) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, i)
+		}
+		// the last point is the first of the other half
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+	hx, hy := make([]float64, len(hull)), make([]float64, len(hull))
+	for i, h := range hull {
+		hx[i], hy[i] = xs[h], ys[h]
+	}
+	return hx, hy
+}
+
+// calligraphyPath is the outline of the nib swept along the points as a
+// filled path
+func calligraphyPath(points []strokePoint) string {
+	var d strings.Builder
+	nibPolygons(points, func(xs, ys []float64) {
+		for i := range xs {
+			cmd := "L"
+			if i == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(&d, "%s%s %s", cmd, svgNumber(xs[i]), svgNumber(ys[i]))
+		}
+		d.WriteString("Z")
+	})
+	return d.String()
+}
@@ -0,0 +1,97 @@
+package render
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// calligraphyLine goes from x0,y0 to x1,y1 with the direction and pressure
+// in every point, direction as the device stores it
+func calligraphyLine(x0, y0, x1, y1 float32, direction, pressure uint8) *v6.LineItem {
+	line := &v6.LineItem{}
+	line.Line.Value.Tool = v6.ToolCalligraphy
+	line.Line.Value.ThicknessScale = 2
+	for i := 0; i <= 10; i++ {
+		t := float32(i) / 10
+		line.Line.Value.Points = append(line.Line.Value.Points, &v6.PenPoint{
+			X: x0 + (x1-x0)*t, Y: y0 + (y1-y0)*t,
+			Width: 64, Direction: direction, Pressure: pressure,
+		})
+	}
+	line.Line.Value.UpdateBoundingRect()
+	return line
+}
+
+// inkAcross counts the pixels with ink on the line through the middle of
+// the image, across the line
+func inkAcross(img image.Image, vertical bool) (ink int) {
+	bounds := img.Bounds()
+	for i := bounds.Min.X; i < bounds.Max.X; i++ {
+		x, y := i, bounds.Dy()/2
+		if !vertical {
+			x, y = bounds.Dx()/2, i
+		}
+		if r, _, _, _ := img.At(x, y).RGBA(); r < 0x8000 {
+			ink++
+		}
+	}
+	return
+}
+
+func TestNibDirection(t *testing.T) {
+	// a quarter turn of the device
+	const down = 64
+	render := func(line *v6.LineItem) image.Image {
+		scene := &v6.Scene{Layers: []*v6.Layer{{IsVisible: true, Lines: []*v6.LineItem{line}}}}
+		return Raster(scene, &Options{Frame: &v6.Rect{MinX: 0, MinY: 0, MaxX: 200, MaxY: 200}, DPI: DPI})
+	}
+	tests := []struct {
+		name     string
+		line     *v6.LineItem
+		vertical bool
+		min, max int
+	}{
+		// the edge of the nib is horizontal
+		{"along the edge", calligraphyLine(0, 100, 200, 100, 0, 255), false, 1, 6},
+		{"across the edge", calligraphyLine(100, 0, 100, 200, down, 255), true, 14, 18},
+		{"across without pressure", calligraphyLine(100, 0, 100, 200, down, 0), true, 5, 8},
+	}
+	for _, test := range tests {
+		if ink := inkAcross(render(test.line), test.vertical); ink < test.min || ink > test.max {
+			t.Errorf("%s: %d pixels of ink, want %d to %d", test.name, ink, test.min, test.max)
+		}
+	}
+
+	// the same points with another direction have another outline, the
+	// direction picks the corners of the nib that lead
+	outline := func(direction uint8) string {
+		s := newStroke(calligraphyLine(0, 100, 200, 120, direction, 200), DefaultPalette)
+		return calligraphyPath(s.Points)
+	}
+	if outline(0) == outline(down/2) {
+		t.Error("the direction does not change the outline")
+	}
+	if outline(0) != outline(0) {
+		t.Error("the outline is not the same for the same points")
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+	xs, ys := convexHull(
+		[]float64{0, 2, 1, 2, 0, 1},
+		[]float64{0, 0, 1, 2, 2, 0})
+	if len(xs) != 4 {
+		t.Fatalf("hull %v %v, want the 4 corners", xs, ys)
+	}
+	area := 0.0
+	for i := range xs {
+		j := (i + 1) % len(xs)
+		area += xs[i]*ys[j] - xs[j]*ys[i]
+	}
+	if math.Abs(area/2-4) > 1e-9 {
+		t.Errorf("hull area %v, want 4", area/2)
+	}
+}
@@ -0,0 +1,216 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// authorColors tell the authors apart, they are used in the order of the
+// author ids
+var authorColors = []color.NRGBA{
+	{0xd6, 0x27, 0x28, 0xff},
+	{0x1f, 0x77, 0xb4, 0xff},
+	{0x2c, 0xa0, 0x2c, 0xff},
+	{0xff, 0x7f, 0x0e, 0xff},
+	{0x94, 0x67, 0xbd, 0xff},
+	{0x17, 0xbe, 0xcf, 0xff},
+	{0x8c, 0x56, 0x4b, 0xff},
+	{0xe3, 0x77, 0xc2, 0xff},
+}
+
+// the color of the anchors and the groups that are anchored
+var anchorColor = color.NRGBA{0xff, 0x00, 0xff, 0xff}
+
+const (
+	debugLineWidth  = 1
+	debugTextSize   = 14
+	debugAnchorSize = 12
+	debugOpacity    = 0.7
+)
+
+// debugAuthors gives every author of the scene a color, the authors of the
+// UUIDMap and the ones only found in the ids of the lines
+func debugAuthors(scene *v6.Scene) map[v6.AuthorId]color.NRGBA {
+	ids := make(map[v6.AuthorId]bool)
+	for id := range scene.UUIDMap.Index2UUID {
+		ids[id] = true
+	}
+	for _, layer := range scene.Layers {
+		for _, line := range layer.Lines {
+			ids[line.Id.Author()] = true
+		}
+	}
+	sorted := make([]int, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, int(id))
+	}
+	sort.Ints(sorted)
+	colors := make(map[v6.AuthorId]color.NRGBA, len(sorted))
+	for i, id := range sorted {
+		colors[v6.AuthorId(id)] = authorColors[i%len(authorColors)]
+	}
+	return colors
+}
+
+// debugId is a short form of the id
+func debugId(id v6.CrdtId) string {
+	return fmt.Sprintf("%d:%d", id.Author(), id.Counter())
+}
+
+// drawDebug draws the bounding box of every item with its id, layer and
+// tool, the anchors of the anchored groups and a legend of the authors.
+// The lines are already drawn in the colors of their authors.
+func drawDebug(c canvas, scene *v6.Scene, frame v6.Rect, opts *Options, colors map[v6.AuthorId]color.NRGBA) {
+	c.BeginLayer(len(scene.Layers), &v6.Layer{Name: "Debug", IsVisible: true})
+	var labels []textLine
+	label := func(x, y float64, col color.NRGBA, format string, args ...interface{}) {
+		labels = append(labels, textLine{
+			X:     x,
+			Y:     y,
+			Font:  regularFont,
+			Size:  debugTextSize,
+			Color: col,
+			Text:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for i, layer := range scene.Layers {
+		if !layer.IsVisible && !opts.HiddenLayers {
+			continue
+		}
+		for _, line := range layer.Lines {
+			bounds := line.Line.Value.Bounds()
+			if bounds.Empty() {
+				continue
+			}
+			col := colors[line.Id.Author()]
+			debugRect(c, bounds, col)
+			label(float64(bounds.MinX), float64(bounds.MinY)-3, col, "%s L%d %s", debugId(line.Id), i, line.Line.Value.Tool)
+		}
+		for _, h := range layer.Highlights {
+			bounds := v6.EmptyRect
+			for _, r := range h.Rectangles {
+				bounds = bounds.Union(v6.Rect{MinX: float32(r.Min.X), MinY: float32(r.Min.Y), MaxX: float32(r.Max.X), MaxY: float32(r.Max.Y)})
+			}
+			if bounds.Empty() {
+				continue
+			}
+			col := colors[h.Id.Author()]
+			debugRect(c, bounds, col)
+			label(float64(bounds.MinX), float64(bounds.MaxY)+debugTextSize, col, "%s L%d highlight", debugId(h.Id), i)
+		}
+	}
+
+	labels = append(labels, debugAnchors(c, scene, opts)...)
+
+	// legend of the authors at the top left of the frame
+	ids := make([]int, 0, len(colors))
+	for id := range colors {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	y := float64(frame.MinY) + 2*debugTextSize
+	for _, id := range ids {
+		name := "?"
+		if u, ok := scene.UUIDMap.Index2UUID[v6.AuthorId(id)]; ok {
+			name = u.String()
+		}
+		label(float64(frame.MinX)+debugTextSize, y, colors[v6.AuthorId(id)], "author %d %s", id, name)
+		y += debugTextSize * 1.5
+	}
+	c.Text(labels)
+	c.EndLayer()
+}
+
+// debugAnchors marks where the groups that are anchored to the text are
+// anchored, with a line to the lines of the group
+func debugAnchors(c canvas, scene *v6.Scene, opts *Options) (labels []textLine) {
+	if scene.Tree == nil {
+		return
+	}
+	positions := make(map[v6.CrdtId][2]float64)
+	deleted := make(map[v6.CrdtId]bool)
+	var origin [2]float64
+	if scene.Text != nil {
+		origin = [2]float64{scene.Text.Position.X, scene.Text.Position.Y}
+		for _, c := range scene.Text.Chars() {
+			deleted[c.Id] = c.Deleted
+		}
+		var lines []textLine
+		lines, positions = layoutParagraphs(scene.Text, opts.palette())
+		for _, line := range lines {
+			runes := []rune(line.Text)
+			for i, id := range line.Ids {
+				positions[id] = [2]float64{line.X + line.Font.width(string(runes[:i]), line.Size), line.Y}
+			}
+		}
+	}
+
+	ids := make([]int, 0, len(scene.Tree.NodeMap))
+	for id := range scene.Tree.NodeMap {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	// labels at the same point are stacked
+	stacked := make(map[[2]float64]int)
+	for _, id := range ids {
+		node := scene.Tree.NodeMap[v6.CrdtId(id)]
+		if node.Value == nil || node.Value.AnchorId.Value == 0 {
+			continue
+		}
+		anchor := node.Value.AnchorId.Value
+		p, found := positions[anchor]
+		if !found {
+			p = origin
+		}
+		c.Stroke(&stroke{
+			Color:  anchorColor,
+			Points: []strokePoint{{X: p[0], Y: p[1], Width: debugAnchorSize, Opacity: debugOpacity}},
+		})
+		text := fmt.Sprintf("anchor %s of %s", debugId(anchor), debugId(node.Id))
+		if deleted[anchor] {
+			text += " deleted"
+		} else if !found {
+			text += " not found"
+		}
+		labels = append(labels, textLine{
+			X:     p[0] + debugAnchorSize,
+			Y:     p[1] + debugTextSize/2 + float64(stacked[p])*debugTextSize*1.5,
+			Font:  regularFont,
+			Size:  debugTextSize,
+			Color: anchorColor,
+			Text:  text,
+		})
+		stacked[p]++
+
+		bounds := v6.EmptyRect
+		for _, line := range scene.GroupLines(node.Id) {
+			bounds = bounds.Union(line.Line.Value.Bounds())
+		}
+		if bounds.Empty() {
+			continue
+		}
+		debugRect(c, bounds, anchorColor)
+		c.Stroke(&stroke{
+			Color: anchorColor,
+			Points: []strokePoint{
+				{X: p[0], Y: p[1], Width: debugLineWidth, Opacity: debugOpacity},
+				{X: float64(bounds.MinX), Y: float64(bounds.MinY), Width: debugLineWidth, Opacity: debugOpacity},
+			},
+		})
+	}
+	return
+}
+
+// debugRect draws the outline of the rectangle
+func debugRect(c canvas, r v6.Rect, col color.NRGBA) {
+	corners := [][2]float32{{r.MinX, r.MinY}, {r.MaxX, r.MinY}, {r.MaxX, r.MaxY}, {r.MinX, r.MaxY}, {r.MinX, r.MinY}}
+	points := make([]strokePoint, len(corners))
+	for i, corner := range corners {
+		points[i] = strokePoint{X: float64(corner[0]), Y: float64(corner[1]), Width: debugLineWidth, Opacity: debugOpacity}
+	}
+	c.Stroke(&stroke{Color: col, Points: points})
+}
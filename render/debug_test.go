@@ -0,0 +1,65 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+	"github.com/google/uuid"
+)
+
+func TestDebugAuthors(t *testing.T) {
+	line := testLine(v6.ToolFineliner, 0, 0, 10, 10)
+	line.Id = v6.NewCrdtId(5, 1)
+	scene := testScene(line)
+	scene.UUIDMap.Add(uuid.New(), 2)
+	scene.UUIDMap.Add(uuid.New(), 1)
+	colors := debugAuthors(scene)
+	for i, author := range []v6.AuthorId{1, 2, 5} {
+		if colors[author] != authorColors[i] {
+			t.Errorf("author %d: color %v, want %v", author, colors[author], authorColors[i])
+		}
+	}
+	if len(colors) != 3 {
+		t.Errorf("%d authors, want 3", len(colors))
+	}
+}
+
+func TestDebug(t *testing.T) {
+	scene := readNotebook(t, "v6_text.rm")
+	var out bytes.Buffer
+	if err := SVG(&out, scene, &Options{Debug: true}); err != nil {
+		t.Fatal(err)
+	}
+	svg := out.String()
+	svgElements(t, out.Bytes())
+	var want []string
+	for _, layer := range scene.Layers {
+		for _, line := range layer.Lines {
+			want = append(want, fmt.Sprintf(">%s L0 %s<", debugId(line.Id), line.Line.Value.Tool))
+		}
+	}
+	for id, u := range scene.UUIDMap.Index2UUID {
+		want = append(want, fmt.Sprintf(">author %d %s<", id, u))
+	}
+	want = append(want,
+		`inkscape:label="Debug"`,
+		// the anchors of the groups are not in this text
+		">anchor 1:27 of 1:24 not found<",
+	)
+	for _, w := range want {
+		if !strings.Contains(svg, w) {
+			t.Errorf("%s missing", w)
+		}
+	}
+	// the lines of the only author that wrote lines
+	color := debugAuthors(scene)[1]
+	if !strings.Contains(svg, fmt.Sprintf(`stroke="#%02x%02x%02x"`, color.R, color.G, color.B)) {
+		t.Error("no line in the color of the author")
+	}
+	if strings.Contains(svg, `stroke="#000000"`) {
+		t.Error("line drawn in its own color")
+	}
+}
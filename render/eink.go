@@ -0,0 +1,120 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// Display is an e-paper panel, the raster output can show the page as the
+// panel does
+type Display struct {
+	Name string
+	// Width and Height of the panel in pixels, the page is scaled to fit
+	Width, Height int
+	// Levels of gray, or of every channel on a color panel
+	Levels int
+	// Color panels keep the colors, the others show them as gray
+	Color bool
+	// Black and White are the darkest and the lightest the panel shows, e-paper
+	// is not as white as a screen
+	Black, White uint8
+}
+
+var (
+	// Remarkable2 the panel of the reMarkable 1 and 2, 226 DPI
+	Remarkable2 = &Display{
+		Name:   "rm2",
+		Width:  PageWidth,
+		Height: PageHeight,
+		Levels: 16,
+		Black:  0x1c,
+		White:  0xe6,
+	}
+	// RemarkablePro the color panel of the Paper Pro, 229 DPI
+	RemarkablePro = &Display{
+		Name:   "pro",
+		Width:  1620,
+		Height: 2160,
+		Levels: 4,
+		Color:  true,
+		Black:  0x24,
+		White:  0xe0,
+	}
+	// Displays the panels by name
+	Displays = map[string]*Display{
+		"rm1": Remarkable2,
+		"rm2": Remarkable2,
+		"pro": RemarkablePro,
+	}
+)
+
+// render draws the page of the device at the size of the panel and reduces
+// the colors to the panel
+func (d *Display) render(scene *v6.Scene, opts *Options) image.Image {
+	frame := Page
+	if opts.Frame != nil || opts.Crop {
+		frame = opts.frame(scene)
+	}
+	panelOpts := *opts
+	panelOpts.Frame = &frame
+	panelOpts.DPI = DPI * float64(d.Width) / PageWidth
+	c := newRasterCanvas(frame, &panelOpts)
+	drawScene(c, scene, frame, &panelOpts)
+	return d.show(c.img)
+}
+
+// show dithers the image to the levels of the panel and maps them to the
+// contrast of the panel
+func (d *Display) show(img *image.RGBA) image.Image {
+	levels := d.Levels
+	if levels < 2 {
+		levels = 2
+	}
+	ramp := make([]uint8, levels)
+	for i := range ramp {
+		ramp[i] = uint8(i * 0xff / (levels - 1))
+	}
+	bounds := img.Bounds()
+
+	if !d.Color {
+		gray := image.NewGray(bounds)
+		draw.Draw(gray, bounds, img, bounds.Min, draw.Src)
+		p := make(color.Palette, levels)
+		for i, v := range ramp {
+			p[i] = color.Gray{v}
+		}
+		paletted := image.NewPaletted(bounds, p)
+		draw.FloydSteinberg.Draw(paletted, bounds, gray, bounds.Min)
+		for i, index := range paletted.Pix {
+			gray.Pix[i] = d.contrast(ramp[index])
+		}
+		return gray
+	}
+
+	p := make(color.Palette, 0, levels*levels*levels)
+	for _, r := range ramp {
+		for _, g := range ramp {
+			for _, b := range ramp {
+				p = append(p, color.RGBA{r, g, b, 0xff})
+			}
+		}
+	}
+	paletted := image.NewPaletted(bounds, p)
+	draw.FloydSteinberg.Draw(paletted, bounds, img, bounds.Min)
+	result := image.NewRGBA(bounds)
+	for i, index := range paletted.Pix {
+		c := p[index].(color.RGBA)
+		result.Pix[i*4] = d.contrast(c.R)
+		result.Pix[i*4+1] = d.contrast(c.G)
+		result.Pix[i*4+2] = d.contrast(c.B)
+		result.Pix[i*4+3] = 0xff
+	}
+	return result
+}
+
+func (d *Display) contrast(v uint8) uint8 {
+	return d.Black + uint8(int(v)*int(d.White-d.Black)/0xff)
+}
@@ -0,0 +1,59 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+func TestDisplayShow(t *testing.T) {
+	// a gradient of every color from black to white
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), uint8((x + y) * 2), 0xff})
+		}
+	}
+	img.Set(0, 0, color.Black)
+	img.Set(63, 63, color.White)
+
+	for name, d := range Displays {
+		shown := d.show(img)
+		values := make(map[uint32]bool)
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				r, g, b, _ := shown.At(x, y).RGBA()
+				for _, v := range []uint32{r >> 8, g >> 8, b >> 8} {
+					if v < uint32(d.Black) || v > uint32(d.White) {
+						t.Fatalf("%s: %d at %d,%d, want %d to %d", name, v, x, y, d.Black, d.White)
+					}
+					values[v] = true
+				}
+				if !d.Color && (r != g || g != b) {
+					t.Fatalf("%s: color at %d,%d", name, x, y)
+				}
+			}
+		}
+		if len(values) > d.Levels {
+			t.Errorf("%s: %d levels, want %d", name, len(values), d.Levels)
+		}
+		if r, _, _, _ := shown.At(0, 0).RGBA(); uint8(r>>8) != d.Black {
+			t.Errorf("%s: black shown as %d, want %d", name, r>>8, d.Black)
+		}
+		if r, _, _, _ := shown.At(63, 63).RGBA(); uint8(r>>8) != d.White {
+			t.Errorf("%s: white shown as %d, want %d", name, r>>8, d.White)
+		}
+	}
+}
+
+func TestDisplaySize(t *testing.T) {
+	scene := testScene(testLine(v6.ToolFineliner, 0, 100, 0, 5000))
+	for name, d := range Displays {
+		// the page of the device, the scrolled part is cut off
+		if bounds := Raster(scene, &Options{Display: d}).Bounds(); bounds.Dx() != d.Width || bounds.Dy() != d.Height {
+			t.Errorf("%s: %v, want %dx%d", name, bounds, d.Width, d.Height)
+		}
+	}
+}
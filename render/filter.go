@@ -0,0 +1,234 @@
+package render
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// Filter selects the content that is drawn, the zero value selects all of
+// it. The hidden layers are selected with HiddenLayers.
+type Filter struct {
+	// Layers by name or by index from 0, all layers when empty
+	Layers []string
+	// Tools of the lines, all tools when empty. The second generation of a
+	// tool is the same tool.
+	Tools []v6.Tool
+	// Colors of the lines and the highlights, all colors when empty
+	Colors []v6.PenColor
+	// NoHighlights leaves out the highlights of the text
+	NoHighlights bool
+	// NoText leaves out the typed text
+	NoText bool
+	// ShowErased draws the ink under the eraser strokes, the lines are
+	// drawn as they were written. The eraser strokes remove the ink from
+	// the lines drawn before them in the same layer otherwise.
+	ShowErased bool
+}
+
+// selected applies the filter of the options, the eraser strokes when there
+// is none. The options that are returned are marked so it is applied once.
+func (o *Options) selected(scene *v6.Scene) (*v6.Scene, *Options) {
+	if o.filtered {
+		return scene, o
+	}
+	f := o.Filter
+	if f == nil {
+		f = &Filter{}
+	}
+	opts := *o
+	opts.filtered = true
+	return f.Apply(scene), &opts
+}
+
+// Apply returns a copy of the scene with the selected content, the scene
+// is not changed
+func (f *Filter) Apply(scene *v6.Scene) *v6.Scene {
+	selected := *scene
+	if f.NoText {
+		selected.Text = nil
+	}
+	selected.Layers = make([]*v6.Layer, len(scene.Layers))
+	for i, layer := range scene.Layers {
+		l := *layer
+		l.Lines, l.Highlights = nil, nil
+		selected.Layers[i] = &l
+		if !f.layer(i, layer, scene.Layers) {
+			continue
+		}
+		lines := layer.Lines
+		if !f.ShowErased {
+			lines = erase(&selected, layer.Lines)
+		}
+		for _, line := range lines {
+			if f.line(line) {
+				l.Lines = append(l.Lines, line)
+			}
+		}
+		if f.NoHighlights {
+			continue
+		}
+		for _, h := range layer.Highlights {
+			if f.color(h.Color) {
+				l.Highlights = append(l.Highlights, h)
+			}
+		}
+	}
+	return &selected
+}
+
+// layer reports whether the layer is selected, a name is taken as an index
+// when no layer has that name
+func (f *Filter) layer(index int, layer *v6.Layer, layers []*v6.Layer) bool {
+	if len(f.Layers) == 0 {
+		return true
+	}
+	for _, name := range f.Layers {
+		if strings.EqualFold(name, layer.Name) {
+			return true
+		}
+		if n, err := strconv.Atoi(name); err == nil && n == index && !hasLayer(layers, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLayer(layers []*v6.Layer, name string) bool {
+	for _, layer := range layers {
+		if strings.EqualFold(name, layer.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) line(line *v6.LineItem) bool {
+	if !f.color(line.Line.Value.Color) {
+		return false
+	}
+	if len(f.Tools) == 0 {
+		return true
+	}
+	for _, tool := range f.Tools {
+		if tool.Base() == line.Line.Value.Tool.Base() {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Filter) color(c v6.PenColor) bool {
+	if len(f.Colors) == 0 {
+		return true
+	}
+	for _, selected := range f.Colors {
+		if selected == c {
+			return true
+		}
+	}
+	return false
+}
+
+// erase applies the eraser strokes to the lines drawn before them, the
+// lines that are hit are replaced by the pieces that are left. The lines
+// are walked back from the last, so every line is cut once by the erasers
+// that came after it. The eraser strokes are left out.
+func erase(scene *v6.Scene, lines []*v6.LineItem) []*v6.LineItem {
+	type eraser struct {
+		shape  v6.Shape
+		bounds v6.Rect
+	}
+	var erasers []eraser
+	erased := make([]*v6.LineItem, 0, len(lines))
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		if shape := eraserShape(line); shape != nil {
+			erasers = append(erasers, eraser{shape, shape.Bounds()})
+			continue
+		}
+		if line.Line.Value.Tool.IsEraser() {
+			continue
+		}
+		pieces := []*v6.LineItem{line}
+		bounds := lineBounds(&line.Line.Value)
+		for _, e := range erasers {
+			if !e.bounds.Intersects(bounds) {
+				continue
+			}
+			var left []*v6.LineItem
+			for _, piece := range pieces {
+				if split, hit := scene.SplitLine(piece, e.shape); hit {
+					left = append(left, split...)
+				} else {
+					left = append(left, piece)
+				}
+			}
+			pieces = left
+		}
+		for j := len(pieces) - 1; j >= 0; j-- {
+			erased = append(erased, pieces[j])
+		}
+	}
+	for i, j := 0, len(erased)-1; i < j; i, j = i+1, j-1 {
+		erased[i], erased[j] = erased[j], erased[i]
+	}
+	return erased
+}
+
+// eraserShape is the area an eraser stroke removes, nil for other lines
+func eraserShape(line *v6.LineItem) v6.Shape {
+	value := &line.Line.Value
+	switch value.Tool {
+	case v6.ToolEraseArea:
+		polygon := make(v6.Polygon, len(value.Points))
+		for i, p := range value.Points {
+			polygon[i] = v6.Point{X: float64(p.X), Y: float64(p.Y)}
+		}
+		return polygon
+	case v6.ToolEraser:
+		return &eraserStroke{line: value}
+	}
+	return nil
+}
+
+// eraserStroke is the area covered by an eraser stroke with its width
+type eraserStroke struct {
+	line *v6.Line
+}
+
+func (e *eraserStroke) Contains(x, y float32) bool {
+	points := e.line.Points
+	for i, p := range points {
+		radius := toolSize(e.line, p) / 2
+		a := p
+		if i > 0 {
+			a = points[i-1]
+		}
+		if segmentDistance(float64(x), float64(y), float64(a.X), float64(a.Y), float64(p.X), float64(p.Y)) <= radius {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *eraserStroke) Bounds() v6.Rect {
+	bounds := v6.EmptyRect
+	for _, p := range e.line.Points {
+		r := float32(toolSize(e.line, p) / 2)
+		bounds = bounds.Union(v6.Rect{MinX: p.X - r, MinY: p.Y - r, MaxX: p.X + r, MaxY: p.Y + r})
+	}
+	return bounds
+}
+
+// segmentDistance is the distance of the point to the segment from a to b
+func segmentDistance(x, y, ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((x-ax)*dx+(y-ay)*dy)/length))
+	}
+	return math.Hypot(x-(ax+t*dx), y-(ay+t*dy))
+}
@@ -0,0 +1,52 @@
+package render
+
+import (
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+func TestFilterErasers(t *testing.T) {
+	ink := func() *v6.LineItem { return testLine(v6.ToolFineliner, 0, 50, 50, 50, 100, 50) }
+	later := func() *v6.LineItem { return testLine(v6.ToolFineliner, 0, 60, 100, 60) }
+	eraser := func() *v6.LineItem { return testLine(v6.ToolEraser, 50, 0, 50, 100) }
+	area := func() *v6.LineItem { return testLine(v6.ToolEraseArea, 40, 40, 60, 40, 60, 55, 40, 55) }
+	tests := []struct {
+		name   string
+		filter *Filter
+		lines  []*v6.LineItem
+		counts []int
+	}{
+		{"no filter", nil, []*v6.LineItem{ink(), eraser(), later()}, []int{2, 2, 2}},
+		{"eraser", &Filter{}, []*v6.LineItem{ink(), eraser(), later()}, []int{2, 2, 2}},
+		{"erase area", &Filter{}, []*v6.LineItem{ink(), area(), later()}, []int{2, 2, 2}},
+		{"eraser first", &Filter{}, []*v6.LineItem{eraser(), ink()}, []int{3}},
+		{"two erasers", &Filter{}, []*v6.LineItem{ink(), eraser(), testLine(v6.ToolEraser, 25, 0, 25, 100)}, []int{2, 2, 2}},
+		{"show erased", &Filter{ShowErased: true}, []*v6.LineItem{ink(), eraser(), later()}, []int{3, 2, 2}},
+		{"tools", &Filter{Tools: []v6.Tool{v6.ToolFineliner}}, []*v6.LineItem{ink(), eraser(), later()}, []int{2, 2, 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scene := testScene(test.lines...)
+			selected, opts := (&Options{Filter: test.filter}).selected(scene)
+			if again, _ := opts.selected(selected); again != selected {
+				t.Error("the filter is applied twice")
+			}
+			var counts []int
+			for _, line := range selected.Layers[0].Lines {
+				counts = append(counts, len(line.Line.Value.Points))
+			}
+			if len(counts) != len(test.counts) {
+				t.Fatalf("points of the lines %v, want %v", counts, test.counts)
+			}
+			for i := range counts {
+				if counts[i] != test.counts[i] {
+					t.Fatalf("points of the lines %v, want %v", counts, test.counts)
+				}
+			}
+			if len(scene.Layers[0].Lines) != len(test.lines) {
+				t.Error("the scene was changed")
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"math"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// Page is the area of the device screen, x = 0 is the center of the page
+var Page = v6.Rect{MinX: -PageWidth / 2, MaxX: PageWidth / 2, MaxY: PageHeight}
+
+// ContentBounds is the area covered by the visible content of the scene,
+// empty for an empty page. It can be anywhere, the page scrolls in every
+// direction.
+func ContentBounds(scene *v6.Scene, opts *Options) v6.Rect {
+	scene, opts = opts.selected(scene)
+	bounds := v6.EmptyRect
+	for _, layer := range scene.Layers {
+		if !layer.IsVisible && !opts.HiddenLayers {
+			continue
+		}
+		for _, line := range layer.Lines {
+			if !line.Line.Value.Tool.IsEraser() {
+				bounds = bounds.Union(lineBounds(&line.Line.Value))
+			}
+		}
+		for _, h := range layer.Highlights {
+			for _, r := range h.Rectangles {
+				bounds = bounds.Union(v6.Rect{
+					MinX: float32(r.Min.X),
+					MinY: float32(r.Min.Y),
+					MaxX: float32(r.Max.X),
+					MaxY: float32(r.Max.Y),
+				})
+			}
+		}
+	}
+	if scene.Text != nil {
+		for _, line := range layoutText(scene.Text, opts.palette()) {
+			bounds = bounds.Union(v6.Rect{
+				MinX: float32(line.X),
+				MinY: float32(line.Y - line.Size),
+				MaxX: float32(line.X + line.Font.width(line.Text, line.Size)),
+				MaxY: float32(line.Y + line.Size/2),
+			})
+		}
+	}
+	return bounds
+}
+
+// lineBounds is the bounding rectangle of the line from the file, lines
+// that were made without one are measured
+func lineBounds(line *v6.Line) v6.Rect {
+	r := line.BoundingRect
+	if r.Empty() {
+		return line.Bounds()
+	}
+	return v6.Rect{MinX: float32(r.Min.X), MinY: float32(r.Min.Y), MaxX: float32(r.Max.X), MaxY: float32(r.Max.Y)}
+}
+
+// CropFrame is the content of the scene with the margin around it, the
+// page of the device when there is no content
+func CropFrame(scene *v6.Scene, opts *Options, margin float64) v6.Rect {
+	bounds := ContentBounds(scene, opts)
+	if bounds.Empty() {
+		return Page
+	}
+	m := float32(margin)
+	return v6.Rect{MinX: bounds.MinX - m, MinY: bounds.MinY - m, MaxX: bounds.MaxX + m, MaxY: bounds.MaxY + m}
+}
+
+// PageFrame is the page that holds all of the content: the device page,
+// extended down and up where the page was scrolled and widened evenly on
+// both sides so x = 0 stays in the center
+func PageFrame(scene *v6.Scene, opts *Options) v6.Rect {
+	frame := Page
+	bounds := ContentBounds(scene, opts)
+	if bounds.Empty() {
+		return frame
+	}
+	half := math.Max(math.Max(-math.Floor(float64(bounds.MinX)), math.Ceil(float64(bounds.MaxX))), PageWidth/2)
+	frame.MinX, frame.MaxX = float32(-half), float32(half)
+	frame.MinY = float32(math.Min(math.Floor(float64(bounds.MinY)), 0))
+	frame.MaxY = float32(math.Max(math.Ceil(float64(bounds.MaxY)), PageHeight))
+	return frame
+}
+
+// SplitPages splits a frame into pages of the device height, from the top of
+// the frame. The last page is filled up to the full height.
+func SplitPages(frame v6.Rect) (pages []v6.Rect) {
+	for top := frame.MinY; top < frame.MaxY; top += PageHeight {
+		pages = append(pages, v6.Rect{MinX: frame.MinX, MinY: top, MaxX: frame.MaxX, MaxY: top + PageHeight})
+	}
+	return
+}
+
+// frame is the area that is drawn
+func (o *Options) frame(scene *v6.Scene) v6.Rect {
+	if o.Frame != nil {
+		return *o.Frame
+	}
+	if o.Crop {
+		return CropFrame(scene, o, o.Margin)
+	}
+	return PageFrame(scene, o)
+}
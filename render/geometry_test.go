@@ -0,0 +1,105 @@
+package render
+
+import (
+	"image"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+func TestPageFrame(t *testing.T) {
+	tests := []struct {
+		name  string
+		scene *v6.Scene
+		frame v6.Rect
+	}{
+		{"empty", testScene(), Page},
+		{"on the page", testScene(testLine(v6.ToolFineliner, -100, 100, 100, 200)), Page},
+		{"scrolled down", testScene(testLine(v6.ToolFineliner, 0, 100, 0, 3000)),
+			v6.Rect{MinX: -PageWidth / 2, MinY: 0, MaxX: PageWidth / 2, MaxY: 3002}},
+		{"scrolled up", testScene(testLine(v6.ToolFineliner, 0, -500, 0, 100)),
+			v6.Rect{MinX: -PageWidth / 2, MinY: -502, MaxX: PageWidth / 2, MaxY: PageHeight}},
+		// widened on both sides so the center stays
+		{"wider on the right", testScene(testLine(v6.ToolFineliner, 0, 100, 1000, 100)),
+			v6.Rect{MinX: -1002, MinY: 0, MaxX: 1002, MaxY: PageHeight}},
+		{"wider on the left", testScene(testLine(v6.ToolFineliner, -1500, 100, 0, 100)),
+			v6.Rect{MinX: -1502, MinY: 0, MaxX: 1502, MaxY: PageHeight}},
+	}
+	for _, test := range tests {
+		if frame := PageFrame(test.scene, &Options{}); frame != test.frame {
+			t.Errorf("%s: frame %v, want %v", test.name, frame, test.frame)
+		}
+	}
+}
+
+func TestCropFrame(t *testing.T) {
+	// a fineliner of width 16 is 4 wide
+	line := func() *v6.LineItem { return testLine(v6.ToolFineliner, 0, 100, 100, 100) }
+	withMargin := v6.Rect{MinX: -12, MinY: 88, MaxX: 112, MaxY: 112}
+
+	hidden := testScene(line())
+	hidden.Layers = append(hidden.Layers, &v6.Layer{Name: "Layer 2", Lines: []*v6.LineItem{testLine(v6.ToolFineliner, 500, 500, 600, 600)}})
+	highlight := testScene(line())
+	highlight.Layers[0].Highlights = []*v6.GlyphRange{{
+		Color:      v6.ColorYellow,
+		Rectangles: []*image.Rectangle{{Min: image.Point{0, 0}, Max: image.Point{50, 20}}},
+	}}
+	// the bounding rectangle of the file is used, it is not measured again
+	stored := line()
+	stored.Line.Value.BoundingRect = image.Rect(-50, -50, 200, 200)
+	measured := line()
+	measured.Line.Value.BoundingRect = image.Rectangle{}
+
+	tests := []struct {
+		name  string
+		scene *v6.Scene
+		frame v6.Rect
+	}{
+		{"empty", testScene(), Page},
+		{"line", testScene(line()), withMargin},
+		{"hidden layer", hidden, withMargin},
+		{"eraser", testScene(line(), testLine(v6.ToolEraser, 500, 500, 600, 600)), withMargin},
+		{"highlight", highlight, v6.Rect{MinX: -12, MinY: -10, MaxX: 112, MaxY: 112}},
+		{"bounding rectangle", testScene(stored), v6.Rect{MinX: -60, MinY: -60, MaxX: 210, MaxY: 210}},
+		{"no bounding rectangle", testScene(measured), withMargin},
+	}
+	for _, test := range tests {
+		if frame := CropFrame(test.scene, &Options{}, 10); frame != test.frame {
+			t.Errorf("%s: frame %v, want %v", test.name, frame, test.frame)
+		}
+	}
+
+	bounds := Raster(testScene(line()), &Options{Crop: true, Margin: 10, DPI: DPI}).Bounds()
+	if bounds.Dx() != 124 || bounds.Dy() != 24 {
+		t.Errorf("cropped raster %v, want 124x24", bounds)
+	}
+}
+
+func TestSplitPages(t *testing.T) {
+	page := func(top float32) v6.Rect {
+		return v6.Rect{MinX: Page.MinX, MinY: top, MaxX: Page.MaxX, MaxY: top + PageHeight}
+	}
+	tests := []struct {
+		name  string
+		frame v6.Rect
+		pages []v6.Rect
+	}{
+		{"one page", Page, []v6.Rect{Page}},
+		{"a bit longer", v6.Rect{MinX: Page.MinX, MaxX: Page.MaxX, MaxY: PageHeight + 1}, []v6.Rect{page(0), page(PageHeight)}},
+		{"scrolled up", v6.Rect{MinX: Page.MinX, MinY: -100, MaxX: Page.MaxX, MaxY: PageHeight}, []v6.Rect{page(-100), page(PageHeight - 100)}},
+		{"three pages", v6.Rect{MinX: Page.MinX, MaxX: Page.MaxX, MaxY: 3 * PageHeight}, []v6.Rect{page(0), page(PageHeight), page(2 * PageHeight)}},
+		{"empty", v6.Rect{MinX: Page.MinX, MaxX: Page.MaxX}, nil},
+	}
+	for _, test := range tests {
+		pages := SplitPages(test.frame)
+		if len(pages) != len(test.pages) {
+			t.Errorf("%s: pages %v, want %v", test.name, pages, test.pages)
+			continue
+		}
+		for i := range pages {
+			if pages[i] != test.pages[i] {
+				t.Errorf("%s: page %d %v, want %v", test.name, i, pages[i], test.pages[i])
+			}
+		}
+	}
+}
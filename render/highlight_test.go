@@ -0,0 +1,61 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+func highlightScene() *v6.Scene {
+	scene := testScene(testLine(v6.ToolFineliner, 50, 0, 50, 100))
+	scene.Layers[0].Highlights = []*v6.GlyphRange{{
+		Color:      v6.ColorYellow,
+		Text:       "a<b",
+		Rectangles: []*image.Rectangle{{Min: image.Point{10, 10}, Max: image.Point{100, 30}}},
+	}}
+	return scene
+}
+
+func TestHighlight(t *testing.T) {
+	if h := newHighlight(&v6.GlyphRange{Text: "nothing"}, DefaultPalette); h != nil {
+		t.Errorf("highlight without rectangles %v", h)
+	}
+
+	frame := &v6.Rect{MinX: 0, MinY: 0, MaxX: 100, MaxY: 100}
+	img := Raster(highlightScene(), &Options{Frame: frame, DPI: DPI})
+	r, g, b, _ := img.At(80, 20).RGBA()
+	if b >= r || b >= g {
+		t.Errorf("highlight drawn %x %x %x, want yellow", r, g, b)
+	}
+	// the text stays readable, the highlight is see through
+	if b < 0x8000 {
+		t.Errorf("highlight not see through: %x %x %x", r, g, b)
+	}
+	if !isInk(img.At(50, 20)) {
+		t.Error("the line is not drawn over the highlight")
+	}
+	if r, g, b, _ := img.At(80, 50).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff {
+		t.Error("highlight drawn outside of its rectangle")
+	}
+
+	var out bytes.Buffer
+	if err := SVG(&out, highlightScene(), &Options{Frame: frame}); err != nil {
+		t.Fatal(err)
+	}
+	svg := out.String()
+	for _, want := range []string{
+		`<g fill="#fefd60" fill-opacity="0.4">`,
+		`<title>a&lt;b</title>`,
+		`<rect x="10" y="10" width="90" height="20"/>`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("%s missing in\n%s", want, svg)
+		}
+	}
+	if strings.Index(svg, "<rect") > strings.Index(svg, "<path") {
+		t.Error("highlight drawn over the line")
+	}
+}
@@ -0,0 +1,287 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// pdfPage is a page of the pdf with the inherited attributes resolved
+type pdfPage struct {
+	Ref       pdfRef
+	Dict      pdfDict
+	MediaBox  v6.Rect
+	CropBox   v6.Rect
+	Rotate    int
+	Resources pdfDict
+}
+
+// inherited are the attributes a page takes from its parents
+type inherited struct {
+	MediaBox  interface{}
+	CropBox   interface{}
+	Rotate    interface{}
+	Resources interface{}
+}
+
+// pages returns the pages in order
+func (r *pdfReader) pages() (pages []*pdfPage, err error) {
+	root := r.dict(r.trailer["Root"])
+	if root == nil {
+		return nil, fmt.Errorf("%w: no catalog", errPdfSyntax)
+	}
+	seen := make(map[pdfRef]bool)
+	var walk func(ref pdfRef, parent inherited) error
+	walk = func(ref pdfRef, parent inherited) error {
+		if seen[ref] {
+			return fmt.Errorf("%w: page tree loop", errPdfSyntax)
+		}
+		seen[ref] = true
+		node := r.dict(ref)
+		if node == nil {
+			return fmt.Errorf("%w: page %d missing", errPdfSyntax, ref.Id)
+		}
+		for key, value := range map[pdfName]*interface{}{
+			"MediaBox":  &parent.MediaBox,
+			"CropBox":   &parent.CropBox,
+			"Rotate":    &parent.Rotate,
+			"Resources": &parent.Resources,
+		} {
+			if v, ok := node[key]; ok {
+				*value = v
+			}
+		}
+		if kids, ok := node["Kids"]; ok {
+			kids, err := r.resolve(kids)
+			if err != nil {
+				return err
+			}
+			list, _ := kids.(pdfArray)
+			for _, kid := range list {
+				if ref, ok := kid.(pdfRef); ok {
+					if err := walk(ref, parent); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+
+		page := &pdfPage{
+			Ref:       ref,
+			Dict:      node,
+			MediaBox:  r.rect(parent.MediaBox),
+			Resources: r.dict(parent.Resources),
+		}
+		if page.MediaBox.Empty() {
+			// letter size
+			page.MediaBox = v6.Rect{MaxX: 612, MaxY: 792}
+		}
+		page.CropBox = page.MediaBox
+		if crop := r.rect(parent.CropBox); !crop.Empty() {
+			page.CropBox = intersect(crop, page.MediaBox)
+		}
+		rotate, _ := r.resolve(parent.Rotate)
+		page.Rotate, _ = pdfInt(rotate)
+		page.Rotate = (page.Rotate%360 + 360) % 360
+		pages = append(pages, page)
+		return nil
+	}
+	ref, ok := root["Pages"].(pdfRef)
+	if !ok {
+		return nil, fmt.Errorf("%w: no page tree", errPdfSyntax)
+	}
+	err = walk(ref, inherited{})
+	return
+}
+
+// rect reads a rectangle, the corners can be in any order
+func (r *pdfReader) rect(v interface{}) v6.Rect {
+	v, _ = r.resolve(v)
+	array, _ := v.(pdfArray)
+	if len(array) != 4 {
+		return v6.EmptyRect
+	}
+	var f [4]float64
+	for i := range f {
+		item, _ := r.resolve(array[i])
+		f[i], _ = pdfFloat(item)
+	}
+	return v6.Rect{
+		MinX: float32(math.Min(f[0], f[2])),
+		MinY: float32(math.Min(f[1], f[3])),
+		MaxX: float32(math.Max(f[0], f[2])),
+		MaxY: float32(math.Max(f[1], f[3])),
+	}
+}
+
+func intersect(a, b v6.Rect) v6.Rect {
+	r := v6.Rect{
+		MinX: float32(math.Max(float64(a.MinX), float64(b.MinX))),
+		MinY: float32(math.Max(float64(a.MinY), float64(b.MinY))),
+		MaxX: float32(math.Min(float64(a.MaxX), float64(b.MaxX))),
+		MaxY: float32(math.Min(float64(a.MaxY), float64(b.MaxY))),
+	}
+	if r.Empty() {
+		return a
+	}
+	return r
+}
+
+func pdfRect(r v6.Rect) pdfArray {
+	return pdfArray{float64(r.MinX), float64(r.MinY), float64(r.MaxX), float64(r.MaxY)}
+}
+
+// pageTransform maps device pixels to the user space of the page. The
+// device shows the visible part of the page as large as it fits the
+// screen, centered and at the top.
+func pageTransform(box v6.Rect, rotate int) v6.Transform {
+	w, h := float64(box.Width()), float64(box.Height())
+	if rotate == 90 || rotate == 270 {
+		w, h = h, w
+	}
+	scale := math.Min(PageWidth/w, PageHeight/h)
+	// to the displayed page, in points from the top left
+	display := v6.Scale(1/scale, 1/scale).Then(v6.Translate(w/2, 0))
+
+	llx, lly := float64(box.MinX), float64(box.MinY)
+	urx, ury := float64(box.MaxX), float64(box.MaxY)
+	var page v6.Transform
+	switch rotate {
+	case 90:
+		page = v6.Transform{B: 1, C: 1, E: llx, F: lly}
+	case 180:
+		page = v6.Transform{A: -1, D: 1, E: urx, F: lly}
+	case 270:
+		page = v6.Transform{B: -1, C: -1, E: urx, F: ury}
+	default:
+		page = v6.Transform{A: 1, D: -1, E: llx, F: ury}
+	}
+	return display.Then(page)
+}
+
+// transformRect returns the bounds of the transformed rectangle
+func transformRect(t v6.Transform, r v6.Rect) v6.Rect {
+	result := v6.EmptyRect
+	for _, corner := range [][2]float32{{r.MinX, r.MinY}, {r.MaxX, r.MinY}, {r.MinX, r.MaxY}, {r.MaxX, r.MaxY}} {
+		x, y := t.Apply(float64(corner[0]), float64(corner[1]))
+		result = result.Union(v6.Rect{MinX: float32(x), MinY: float32(y), MaxX: float32(x), MaxY: float32(y)})
+	}
+	return result
+}
+
+// Overlay draws the scenes on the pages of the pdf and writes the file with
+// an incremental update, the original content is not changed. scenes[i] is
+// drawn on page i, nil scenes are skipped. Pages are enlarged where the
+// content goes past them.
+func Overlay(w io.Writer, source []byte, scenes []*v6.Scene, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	// the page of the pdf is the template
+	pageOpts := *opts
+	pageOpts.Template = nil
+	opts = &pageOpts
+	r, err := readPdf(source)
+	if err != nil {
+		return err
+	}
+	pages, err := r.pages()
+	if err != nil {
+		return err
+	}
+	if len(scenes) > len(pages) {
+		return fmt.Errorf("%d scenes for %d pages", len(scenes), len(pages))
+	}
+
+	p := appendPdfWriter(w, source, r.size())
+	for i, scene := range scenes {
+		if scene != nil {
+			scene, opts := opts.selected(scene)
+			overlayPage(p, r, pages[i], scene, opts)
+		}
+	}
+
+	trailer := fmt.Sprintf("/Root %s /Prev %d", formatPdf(r.trailer["Root"]), r.startxref)
+	for _, key := range []pdfName{"Info", "ID"} {
+		if v, ok := r.trailer[key]; ok {
+			trailer += fmt.Sprintf(" /%s %s", key, formatPdf(v))
+		}
+	}
+	if r.xrefStream {
+		return p.closeStream(trailer)
+	}
+	return p.closeTable(trailer)
+}
+
+// overlayPage draws the scene into a form that is painted after the page
+// content, the page is written again with the form added
+func overlayPage(p *pdfWriter, r *pdfReader, page *pdfPage, scene *v6.Scene, opts *Options) {
+	bounds := ContentBounds(scene, opts)
+	if bounds.Empty() {
+		return
+	}
+	t := pageTransform(page.CropBox, page.Rotate)
+	c := newPdfCanvas(p, false)
+	drawScene(c, scene, bounds, opts)
+
+	dict := make(pdfDict, len(page.Dict)+2)
+	for key, value := range page.Dict {
+		dict[key] = value
+	}
+	// content past the page, scrolled or outside of the crop box
+	extended := transformRect(t, bounds)
+	if page.CropBox.Union(extended) != page.CropBox {
+		dict["CropBox"] = pdfRect(page.CropBox.Union(extended))
+		dict["MediaBox"] = pdfRect(page.MediaBox.Union(extended))
+	}
+
+	form := p.reserve()
+	p.stream(form, fmt.Sprintf("/Type /XObject /Subtype /Form /BBox %s /Matrix [%s %s %s %s %s %s] /Resources %s",
+		formatPdf(pdfRect(bounds)), pdfNumber6(t.A), pdfNumber6(t.B), pdfNumber6(t.C), pdfNumber6(t.D), pdfNumber6(t.E), pdfNumber6(t.F),
+		c.resources()), c.content.Bytes())
+
+	resources := make(pdfDict, len(page.Resources)+1)
+	for key, value := range page.Resources {
+		resources[key] = value
+	}
+	xobjects := make(pdfDict)
+	for key, value := range r.dict(page.Resources["XObject"]) {
+		xobjects[key] = value
+	}
+	name := pdfName("RmOverlay")
+	for i := 1; xobjects[name] != nil; i++ {
+		name = pdfName(fmt.Sprintf("RmOverlay%d", i))
+	}
+	xobjects[name] = pdfRef{Id: form}
+	resources["XObject"] = xobjects
+	dict["Resources"] = resources
+
+	// the page content is wrapped so its graphics state does not leak
+	begin, end := p.reserve(), p.reserve()
+	p.stream(begin, "", []byte("q\n"))
+	p.stream(end, "", []byte(fmt.Sprintf("Q q %s Do Q\n", formatPdf(name))))
+	contents := pdfArray{pdfRef{Id: begin}}
+	old := page.Dict["Contents"]
+	if resolved, err := r.resolve(old); err == nil {
+		if array, ok := resolved.(pdfArray); ok {
+			old = array
+		}
+	}
+	switch old := old.(type) {
+	case pdfArray:
+		contents = append(contents, old...)
+	case nil:
+	default:
+		contents = append(contents, old)
+	}
+	dict["Contents"] = append(contents, pdfRef{Id: end})
+	p.replace(page.Ref, formatPdf(dict))
+}
+
+// pdfNumber6 keeps more precision for matrices
+func pdfNumber6(f float64) string {
+	return formatPdf(math.Round(f*1e6) / 1e6)
+}
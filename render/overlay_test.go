@@ -0,0 +1,179 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+func TestOverlayTwice(t *testing.T) {
+	for _, name := range notebooks {
+		scene := readNotebook(t, name)
+		var source bytes.Buffer
+		if err := PDF(&source, scene, nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		files := [][]byte{source.Bytes()}
+		for i := 0; i < 2; i++ {
+			var out bytes.Buffer
+			err := Overlay(&out, files[len(files)-1], []*v6.Scene{scene}, nil)
+			if err != nil {
+				t.Fatalf("%s: overlay %d: %v", name, i+1, err)
+			}
+			files = append(files, out.Bytes())
+		}
+
+		last := files[len(files)-1]
+		for i := 0; i < len(files)-1; i++ {
+			if !bytes.HasPrefix(files[i+1], files[i]) {
+				t.Errorf("%s: update %d changed the file it was added to", name, i+1)
+			}
+		}
+		r, err := readPdf(last)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// every file has one section more, the older ones are found by /Prev
+		offset := r.startxref
+		for i := len(files) - 1; i >= 0; i-- {
+			previous, err := readPdf(files[i])
+			if err != nil {
+				t.Fatalf("%s: file %d: %v", name, i, err)
+			}
+			if offset != previous.startxref {
+				t.Fatalf("%s: section %d at %d, want %d", name, i, offset, previous.startxref)
+			}
+			section := &pdfReader{data: last, xref: make(map[int]xrefEntry), objects: make(map[int]interface{})}
+			trailer, err := section.readXref(offset)
+			if err != nil {
+				t.Fatalf("%s: section %d: %v", name, i, err)
+			}
+			for id, entry := range section.xref {
+				if !bytes.HasPrefix(last[entry.Offset:], []byte(fmt.Sprintf("%d %d obj", id, entry.Gen))) {
+					t.Errorf("%s: section %d: object %d is not at %d", name, i, id, entry.Offset)
+				}
+			}
+			prev, ok := pdfInt(trailer["Prev"])
+			if i == 0 {
+				if ok {
+					t.Errorf("%s: the first section has /Prev %d", name, prev)
+				}
+				break
+			}
+			if !ok {
+				t.Fatalf("%s: section %d has no /Prev", name, i)
+			}
+			offset = prev
+		}
+
+		pages, err := r.pages()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(pages) != 1 {
+			t.Errorf("%s: %d pages, want 1", name, len(pages))
+		}
+	}
+}
+
+// xrefStreamPdf is a page with a cross reference stream, the entries are
+// not compressed. dict is added to the dictionary of the stream.
+func xrefStreamPdf(dict string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.5\n")
+	var offsets []int
+	for _, object := range []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 100 100] >>",
+	} {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), object)
+	}
+	xref := b.Len()
+	offsets = append(offsets, xref)
+	entries := []byte{0, 0, 0, 0xff}
+	for _, offset := range offsets {
+		entries = append(entries, 1, byte(offset>>8), byte(offset), 0)
+	}
+	fmt.Fprintf(&b, "4 0 obj\n<< /Type /XRef /Size 5 /W [1 2 1] /Root 1 0 R /Length %d %s >>\nstream\n", len(entries), dict)
+	b.Write(entries)
+	fmt.Fprintf(&b, "\nendstream\nendobj\nstartxref\n%d\n%%%%EOF\n", xref)
+	return b.Bytes()
+}
+
+var startxref = regexp.MustCompile(`startxref\s+\d+`)
+
+func TestReadBrokenPdf(t *testing.T) {
+	scene := testScene(testLine(v6.ToolFineliner, 10, 10, 100, 100))
+	var table bytes.Buffer
+	if err := PDF(&table, scene, nil); err != nil {
+		t.Fatal(err)
+	}
+	var updated bytes.Buffer
+	if err := Overlay(&updated, table.Bytes(), []*v6.Scene{scene}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// the trailer comes after the cross reference table, changing it does
+	// not move any object
+	trailer := func(data []byte, old, new string) []byte {
+		i := bytes.LastIndex(data, []byte("trailer"))
+		return append(append([]byte{}, data[:i]...), strings.Replace(string(data[i:]), old, new, 1)...)
+	}
+	prev := regexp.MustCompile(`/Prev \d+`)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		syntax bool
+	}{
+		{"negative startxref", startxref.ReplaceAll(table.Bytes(), []byte("startxref\n-20")), true},
+		{"startxref past the end", startxref.ReplaceAll(table.Bytes(), []byte("startxref\n99999999")), true},
+		{"negative prev", prev.ReplaceAll(updated.Bytes(), []byte("/Prev -1")), true},
+		{"prev past the end", prev.ReplaceAll(updated.Bytes(), []byte("/Prev 99999999")), true},
+		{"negative xref stream", trailer(table.Bytes(), "/Size", "/XRefStm -7 /Size"), true},
+		{"xref stream past the end", trailer(table.Bytes(), "/Size", "/XRefStm 99999999 /Size"), true},
+		{"huge xref table", bytes.Replace(table.Bytes(), []byte("xref\n0 "), []byte("xref\n0 999999999999"), 1), true},
+		{"no startxref", table.Bytes()[:table.Len()/2], true},
+		{"empty", nil, true},
+		{"invalid field width", xrefStreamPdf("/W [1 2 99]"), true},
+	}
+	for _, test := range tests {
+		_, err := readPdf(test.data)
+		if err == nil || test.syntax && !errors.Is(err, errPdfSyntax) {
+			t.Errorf("%s: error %v, want %v", test.name, err, errPdfSyntax)
+		}
+		if err := Overlay(&bytes.Buffer{}, test.data, []*v6.Scene{scene}, nil); err == nil {
+			t.Errorf("%s: overlay without an error", test.name)
+		}
+	}
+
+	// the counts of a cross reference stream end with its data
+	for _, dict := range []string{"", "/Index [0 999999999999]", "/Index [0 5 9 999999999999]", "/Size 999999999999"} {
+		data := xrefStreamPdf(dict)
+		if dict != "" {
+			data = bytes.Replace(data, []byte("/Size 5 "), nil, 1)
+		}
+		r, err := readPdf(data)
+		if err != nil {
+			t.Fatalf("%q: %v", dict, err)
+		}
+		if len(r.xref) != 4 {
+			t.Errorf("%q: %d objects, want 4", dict, len(r.xref))
+		}
+		if err := Overlay(&bytes.Buffer{}, data, []*v6.Scene{scene}, nil); err != nil {
+			t.Errorf("%q: %v", dict, err)
+		}
+	}
+
+	// every cut of the file is an error and not a panic
+	for n := 0; n < updated.Len(); n += 13 {
+		readPdf(updated.Bytes()[:n])
+		Overlay(&bytes.Buffer{}, updated.Bytes()[:n], []*v6.Scene{scene}, nil)
+	}
+}
@@ -0,0 +1,150 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// Palette maps the colors of the device to the colors that are drawn
+type Palette struct {
+	Background color.NRGBA
+	Colors     map[v6.PenColor]color.NRGBA
+}
+
+// DefaultPalette the colors as shown on the device
+var DefaultPalette = &Palette{
+	Background: color.NRGBA{0xff, 0xff, 0xff, 0xff},
+	Colors: map[v6.PenColor]color.NRGBA{
+		v6.ColorBlack:       {0x00, 0x00, 0x00, 0xff},
+		v6.ColorGray:        {0x90, 0x90, 0x90, 0xff},
+		v6.ColorWhite:       {0xff, 0xff, 0xff, 0xff},
+		v6.ColorYellow:      {0xfe, 0xfd, 0x60, 0xff},
+		v6.ColorGreen:       {0xa9, 0xfa, 0x5c, 0xff},
+		v6.ColorPink:        {0xff, 0x55, 0xcf, 0xff},
+		v6.ColorBlue:        {0x4e, 0x69, 0xc9, 0xff},
+		v6.ColorRed:         {0xb3, 0x3e, 0x39, 0xff},
+		v6.ColorGrayOverlap: {0x7d, 0x7d, 0x7d, 0xff},
+		v6.ColorHighlight:   {0xfe, 0xfd, 0x60, 0xff},
+		v6.ColorGreen2:      {0xa1, 0xd8, 0x7d, 0xff},
+		v6.ColorCyan:        {0x8b, 0xd0, 0xe5, 0xff},
+		v6.ColorMagenta:     {0xb7, 0x82, 0xcd, 0xff},
+		v6.ColorYellow2:     {0xf7, 0xe8, 0x51, 0xff},
+	},
+}
+
+// DarkPalette light ink on a dark page
+var DarkPalette = DefaultPalette.With(color.NRGBA{0x1e, 0x1e, 0x1e, 0xff}, map[v6.PenColor]color.NRGBA{
+	v6.ColorBlack:       {0xf0, 0xf0, 0xf0, 0xff},
+	v6.ColorGray:        {0xa0, 0xa0, 0xa0, 0xff},
+	v6.ColorWhite:       {0x1e, 0x1e, 0x1e, 0xff},
+	v6.ColorBlue:        {0x8a, 0xa4, 0xff, 0xff},
+	v6.ColorRed:         {0xff, 0x7a, 0x70, 0xff},
+	v6.ColorGrayOverlap: {0x90, 0x90, 0x90, 0xff},
+})
+
+// PrintPalette darker ink and lighter highlights, for paper
+var PrintPalette = DefaultPalette.With(color.NRGBA{0xff, 0xff, 0xff, 0xff}, map[v6.PenColor]color.NRGBA{
+	v6.ColorGray:        {0x60, 0x60, 0x60, 0xff},
+	v6.ColorBlue:        {0x1f, 0x3a, 0x93, 0xff},
+	v6.ColorRed:         {0x9b, 0x1c, 0x1c, 0xff},
+	v6.ColorGreen:       {0x2e, 0x7d, 0x32, 0xff},
+	v6.ColorPink:        {0xc2, 0x18, 0x5b, 0xff},
+	v6.ColorYellow:      {0xff, 0xf5, 0x9d, 0xff},
+	v6.ColorHighlight:   {0xff, 0xf5, 0x9d, 0xff},
+	v6.ColorYellow2:     {0xff, 0xf5, 0x9d, 0xff},
+	v6.ColorGrayOverlap: {0xd0, 0xd0, 0xd0, 0xff},
+})
+
+// Palettes the built in palettes by name
+var Palettes = map[string]*Palette{
+	"default": DefaultPalette,
+	"dark":    DarkPalette,
+	"print":   PrintPalette,
+}
+
+// With returns a copy of the palette with another background and some
+// colors replaced
+func (p *Palette) With(background color.NRGBA, colors map[v6.PenColor]color.NRGBA) *Palette {
+	result := &Palette{
+		Background: background,
+		Colors:     make(map[v6.PenColor]color.NRGBA, len(p.Colors)),
+	}
+	for c, rgba := range p.Colors {
+		result.Colors[c] = rgba
+	}
+	for c, rgba := range colors {
+		result.Colors[c] = rgba
+	}
+	return result
+}
+
+// Color returns the color to draw, unknown colors are drawn black
+func (p *Palette) Color(c v6.PenColor) color.NRGBA {
+	if rgba, ok := p.Colors[c]; ok {
+		return rgba
+	}
+	return p.Colors[v6.ColorBlack]
+}
+
+// LineColor returns the color of the line, the ARGB color of the line
+// takes precedence over the palette
+func (p *Palette) LineColor(line *v6.Line) color.NRGBA {
+	if line.ARGB != 0 {
+		r, g, b, a := line.ARGB.Components()
+		return color.NRGBA{r, g, b, a}
+	}
+	return p.Color(line.Color)
+}
+
+// ReadPalette reads a json object of color names and hex colors, for
+// example {"background": "#1e1e1e", "black": "#ffffff"}. Colors that are
+// not in the file are taken from the default palette.
+func ReadPalette(r io.Reader) (palette *Palette, err error) {
+	var names map[string]string
+	err = json.NewDecoder(r).Decode(&names)
+	if err != nil {
+		return
+	}
+	background := DefaultPalette.Background
+	colors := make(map[v6.PenColor]color.NRGBA)
+	for name, value := range names {
+		var rgba color.NRGBA
+		rgba, err = parseHexColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("color %s: %w", name, err)
+		}
+		if strings.EqualFold(name, "background") {
+			background = rgba
+			continue
+		}
+		c, ok := v6.ParsePenColor(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown color: %s", name)
+		}
+		colors[c] = rgba
+	}
+	return DefaultPalette.With(background, colors), nil
+}
+
+// parseHexColor parses #rrggbb or #rrggbbaa
+func parseHexColor(s string) (c color.NRGBA, err error) {
+	c.A = 0xff
+	switch len(s) {
+	case 7:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 9:
+		_, err = fmt.Sscanf(s, "#%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		err = fmt.Errorf("invalid color: %s", s)
+	}
+	return
+}
+
+func isWhite(c color.NRGBA) bool {
+	return c == color.NRGBA{0xff, 0xff, 0xff, 0xff}
+}
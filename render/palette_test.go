@@ -0,0 +1,72 @@
+package render
+
+import (
+	"image/color"
+	"strings"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+func TestPalettes(t *testing.T) {
+	for name, palette := range Palettes {
+		for c := v6.ColorBlack; c <= v6.ColorYellow2; c++ {
+			if _, ok := palette.Colors[c]; !ok {
+				t.Errorf("%s: no %v", name, c)
+			}
+		}
+	}
+	if c := DefaultPalette.Color(v6.PenColor(100)); c != DefaultPalette.Colors[v6.ColorBlack] {
+		t.Errorf("unknown color drawn %v", c)
+	}
+	if DarkPalette.Colors[v6.ColorBlack] == DefaultPalette.Colors[v6.ColorBlack] {
+		t.Error("the dark palette changed the default one")
+	}
+}
+
+func TestReadPalette(t *testing.T) {
+	palette, err := ReadPalette(strings.NewReader(`{"Background": "#102030", "blue": "#0000ff80", "gray overlap": "#111111"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		got, want color.NRGBA
+	}{
+		{palette.Background, color.NRGBA{0x10, 0x20, 0x30, 0xff}},
+		{palette.Color(v6.ColorBlue), color.NRGBA{0, 0, 0xff, 0x80}},
+		{palette.Color(v6.ColorGrayOverlap), color.NRGBA{0x11, 0x11, 0x11, 0xff}},
+		{palette.Color(v6.ColorRed), DefaultPalette.Colors[v6.ColorRed]},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("color %v, want %v", test.got, test.want)
+		}
+	}
+	for _, invalid := range []string{`{"purple": "#ffffff"}`, `{"red": "red"}`, `{"red": "#12345"}`, `[]`} {
+		if _, err := ReadPalette(strings.NewReader(invalid)); err == nil {
+			t.Errorf("%s: no error", invalid)
+		}
+	}
+}
+
+// the shader has its own color, its alpha is drawn as opacity
+func TestLineColor(t *testing.T) {
+	line := testLine(v6.ToolShader, 0, 0, 10, 10)
+	line.Line.Value.Color = v6.ColorRed
+	line.Line.Value.ARGB = 0x80123456
+	if c := DefaultPalette.LineColor(&line.Line.Value); c != (color.NRGBA{0x12, 0x34, 0x56, 0x80}) {
+		t.Errorf("line color %v", c)
+	}
+	s := newStroke(line, DefaultPalette)
+	if s.Color.A != 0xff {
+		t.Errorf("stroke alpha %d", s.Color.A)
+	}
+	if want := quantize(0.25 * 0x80 / 0xff); s.Points[0].Opacity != want {
+		t.Errorf("opacity %v, want %v", s.Points[0].Opacity, want)
+	}
+
+	line.Line.Value.ARGB = 0
+	if c := PrintPalette.LineColor(&line.Line.Value); c != PrintPalette.Colors[v6.ColorRed] {
+		t.Errorf("line color %v without argb", c)
+	}
+}
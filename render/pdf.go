@@ -0,0 +1,427 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// points per pixel of the device
+const pdfScale = 72.0 / DPI
+
+// pdfWriter writes numbered objects and the cross reference table, either
+// of a new file or of an update appended to an existing file
+type pdfWriter struct {
+	w   *bufio.Writer
+	pos int
+	// next free object number
+	next    int
+	offsets map[int]int
+	gens    map[int]int
+}
+
+func newPdfWriter(w io.Writer) *pdfWriter {
+	p := &pdfWriter{
+		w:       bufio.NewWriter(w),
+		next:    1,
+		offsets: make(map[int]int),
+		gens:    make(map[int]int),
+	}
+	p.printf("%%PDF-1.5\n%%\xe2\xe3\xcf\xd3\n")
+	return p
+}
+
+// appendPdfWriter copies the file, the objects are written as an
+// incremental update. size is the size from the trailer of the file.
+func appendPdfWriter(w io.Writer, file []byte, size int) *pdfWriter {
+	p := &pdfWriter{
+		w:       bufio.NewWriter(w),
+		next:    size,
+		offsets: make(map[int]int),
+		gens:    make(map[int]int),
+	}
+	n, _ := p.w.Write(file)
+	p.pos += n
+	if len(file) > 0 && file[len(file)-1] != '\n' && file[len(file)-1] != '\r' {
+		p.printf("\n")
+	}
+	return p
+}
+
+func (p *pdfWriter) printf(format string, args ...interface{}) {
+	n, _ := fmt.Fprintf(p.w, format, args...)
+	p.pos += n
+}
+
+// reserve returns the number of an object that is written later
+func (p *pdfWriter) reserve() int {
+	id := p.next
+	p.next++
+	return id
+}
+
+// replace overwrites an object of the file in the update
+func (p *pdfWriter) replace(ref pdfRef, dict string) {
+	p.gens[ref.Id] = ref.Gen
+	p.object(ref.Id, dict)
+}
+
+func (p *pdfWriter) object(id int, dict string) {
+	p.offsets[id] = p.pos
+	p.printf("%d %d obj\n%s\nendobj\n", id, p.gens[id], dict)
+}
+
+// stream writes a compressed stream object
+func (p *pdfWriter) stream(id int, dict string, data []byte) {
+	var buffer bytes.Buffer
+	z := zlib.NewWriter(&buffer)
+	z.Write(data)
+	z.Close()
+	p.offsets[id] = p.pos
+	p.printf("%d %d obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", id, p.gens[id], dict, buffer.Len())
+	n, _ := p.w.Write(buffer.Bytes())
+	p.pos += n
+	p.printf("\nendstream\nendobj\n")
+}
+
+// ids returns the written objects in runs of consecutive numbers
+func (p *pdfWriter) ids() (runs [][]int) {
+	ids := make([]int, 0, len(p.offsets))
+	for id := range p.offsets {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1]+1 {
+			runs = append(runs, nil)
+		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], id)
+	}
+	return
+}
+
+func (p *pdfWriter) close(root int) error {
+	return p.closeTable(fmt.Sprintf("/Root %d 0 R", root))
+}
+
+// closeTable writes a cross reference table, the trailer gets the size
+func (p *pdfWriter) closeTable(trailer string) error {
+	xref := p.pos
+	p.printf("xref\n")
+	if _, ok := p.offsets[1]; ok {
+		// a new file
+		p.offsets[0] = -1
+	}
+	for _, run := range p.ids() {
+		p.printf("%d %d\n", run[0], len(run))
+		for _, id := range run {
+			if id == 0 {
+				p.printf("0000000000 65535 f \n")
+				continue
+			}
+			p.printf("%010d %05d n \n", p.offsets[id], p.gens[id])
+		}
+	}
+	p.printf("trailer\n<< /Size %d %s >>\nstartxref\n%d\n%%%%EOF\n", p.next, trailer, xref)
+	return p.w.Flush()
+}
+
+// closeStream writes a cross reference stream, for updates of files that
+// use them
+func (p *pdfWriter) closeStream(trailer string) error {
+	id := p.reserve()
+	p.offsets[id] = p.pos
+	var index strings.Builder
+	var data []byte
+	for _, run := range p.ids() {
+		fmt.Fprintf(&index, " %d %d", run[0], len(run))
+		for _, id := range run {
+			offset := p.offsets[id]
+			data = append(data, 1,
+				byte(offset>>24), byte(offset>>16), byte(offset>>8), byte(offset),
+				byte(p.gens[id]>>8), byte(p.gens[id]))
+		}
+	}
+	xref := p.pos
+	p.stream(id, fmt.Sprintf("/Type /XRef /Size %d /W [1 4 2] /Index [%s ] %s", p.next, index.String(), trailer), data)
+	p.printf("startxref\n%d\n%%%%EOF\n", xref)
+	return p.w.Flush()
+}
+
+type pdfCanvas struct {
+	content bytes.Buffer
+	// optional content groups are only used for whole pages
+	optional bool
+	// optional content group of every layer
+	layers []int
+	hidden []int
+	names  []string
+	alphas map[uint8]string
+	fonts  []*pdfFont
+	// image objects of the templates, named Im1, Im2...
+	images []*pdfImage
+	p      *pdfWriter
+}
+
+type pdfImage struct {
+	Template *Template
+	Id       int
+}
+
+func newPdfCanvas(p *pdfWriter, optional bool) *pdfCanvas {
+	c := &pdfCanvas{
+		p:        p,
+		optional: optional,
+		alphas:   make(map[uint8]string),
+	}
+	c.content.WriteString("1 J 1 j\n")
+	return c
+}
+
+// PDF writes the scene as a pdf in the physical size of the page, every
+// layer is an optional content group. The scene is a form that is drawn
+// on every page when the frame is split.
+func PDF(w io.Writer, scene *v6.Scene, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	scene, opts = opts.selected(scene)
+	// hidden layers are switched off in the viewer instead
+	pdfOpts := *opts
+	pdfOpts.HiddenLayers = true
+
+	frame := opts.frame(scene)
+	frames := []v6.Rect{frame}
+	if opts.Split {
+		frames = SplitPages(frame)
+	}
+
+	c := newPdfCanvas(newPdfWriter(w), true)
+	catalog := c.p.reserve()
+	pages := c.p.reserve()
+	form := c.p.reserve()
+
+	if bg := opts.palette().Background; !isWhite(bg) {
+		fmt.Fprintf(&c.content, "%s %s %s rg %s %s %s %s re f\n",
+			pdfColor(bg.R), pdfColor(bg.G), pdfColor(bg.B),
+			pdfNumber(float64(frame.MinX)), pdfNumber(float64(frame.MinY)), pdfNumber(float64(frame.Width())), pdfNumber(float64(frame.Height())))
+	}
+	drawScene(c, scene, frame, &pdfOpts)
+	c.p.stream(form, fmt.Sprintf("/Type /XObject /Subtype /Form /BBox %s /Resources %s",
+		formatPdf(pdfRect(frame)), c.resources()), c.content.Bytes())
+
+	var kids strings.Builder
+	for _, f := range frames {
+		page := c.p.reserve()
+		contents := c.p.reserve()
+		// page pixels to points, y goes up in pdf
+		c.p.stream(contents, "", []byte(fmt.Sprintf("q %s 0 0 %s %s %s cm /Scene Do Q\n",
+			pdfNumber(pdfScale), pdfNumber(-pdfScale),
+			pdfNumber(-float64(f.MinX)*pdfScale), pdfNumber(float64(f.MaxY)*pdfScale))))
+		c.p.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R /Resources << /XObject << /Scene %d 0 R >> >> >>",
+			pages, pdfNumber(float64(f.Width())*pdfScale), pdfNumber(float64(f.Height())*pdfScale), contents, form))
+		fmt.Fprintf(&kids, " %d 0 R", page)
+	}
+
+	var ocgs, off strings.Builder
+	for i, id := range c.layers {
+		c.p.object(id, fmt.Sprintf("<< /Type /OCG /Name %s >>", pdfString(c.names[i])))
+		fmt.Fprintf(&ocgs, " %d 0 R", id)
+	}
+	for _, id := range c.hidden {
+		fmt.Fprintf(&off, " %d 0 R", id)
+	}
+
+	c.p.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R /OCProperties << /OCGs [%s ] /D << /Order [%s ] /OFF [%s ] >> >> >>",
+		pages, ocgs.String(), ocgs.String(), off.String()))
+	c.p.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s ] /Count %d >>", kids.String(), len(frames)))
+	return c.p.close(catalog)
+}
+
+// resources writes the fonts and returns the resources of the content
+func (c *pdfCanvas) resources() string {
+	var properties, extGStates, fonts, xobjects strings.Builder
+	for i, id := range c.layers {
+		fmt.Fprintf(&properties, " /OC%d %d 0 R", i+1, id)
+	}
+	alphas := make([]int, 0, len(c.alphas))
+	for alpha := range c.alphas {
+		alphas = append(alphas, int(alpha))
+	}
+	sort.Ints(alphas)
+	for _, alpha := range alphas {
+		a := pdfNumber(float64(alpha) / 0xff)
+		fmt.Fprintf(&extGStates, " /%s << /Type /ExtGState /CA %s /ca %s >>", c.alphas[uint8(alpha)], a, a)
+	}
+	for _, font := range c.fonts {
+		font.write(c.p)
+		fmt.Fprintf(&fonts, " /%s %d 0 R", font.Name, font.Id)
+	}
+	for i, im := range c.images {
+		fmt.Fprintf(&xobjects, " /Im%d %d 0 R", i+1, im.Id)
+	}
+	return fmt.Sprintf("<< /Font <<%s >> /ExtGState <<%s >> /Properties <<%s >> /XObject <<%s >> >>",
+		fonts.String(), extGStates.String(), properties.String(), xobjects.String())
+}
+
+func (c *pdfCanvas) BeginLayer(index int, layer *v6.Layer) {
+	if !c.optional {
+		return
+	}
+	id := c.p.reserve()
+	c.layers = append(c.layers, id)
+	c.names = append(c.names, layer.Name)
+	if !layer.IsVisible {
+		c.hidden = append(c.hidden, id)
+	}
+	fmt.Fprintf(&c.content, "/OC /OC%d BDC\n", len(c.layers))
+}
+
+func (c *pdfCanvas) EndLayer() {
+	if c.optional {
+		c.content.WriteString("EMC\n")
+	}
+}
+
+// Stroke writes a path for every run of points with the same width and
+// opacity
+func (c *pdfCanvas) Stroke(s *stroke) {
+	fmt.Fprintf(&c.content, "q %s %s %s RG\n", pdfColor(s.Color.R), pdfColor(s.Color.G), pdfColor(s.Color.B))
+	opacity := 1.0
+	for _, run := range s.runs(func(a, b strokePoint) bool {
+		return pdfNumber(a.Width) == pdfNumber(b.Width) && a.Opacity == b.Opacity
+	}) {
+		last := run[len(run)-1]
+		if last.Opacity != opacity {
+			opacity = last.Opacity
+			fmt.Fprintf(&c.content, "/%s gs ", c.alpha(uint8(math.Round(opacity*0xff))))
+		}
+		fmt.Fprintf(&c.content, "%s w", pdfNumber(last.Width))
+		for j, p := range run {
+			op := "l"
+			if j == 0 {
+				op = "m"
+			}
+			fmt.Fprintf(&c.content, " %s %s %s", pdfNumber(p.X), pdfNumber(p.Y), op)
+		}
+		c.content.WriteString(" S\n")
+	}
+	c.content.WriteString("Q\n")
+}
+
+// Highlight fills the rectangles
+func (c *pdfCanvas) Highlight(h *highlight) {
+	fmt.Fprintf(&c.content, "q %s %s %s rg /%s gs\n",
+		pdfColor(h.Color.R), pdfColor(h.Color.G), pdfColor(h.Color.B), c.alpha(h.Color.A))
+	for _, r := range h.Rects {
+		fmt.Fprintf(&c.content, "%s %s %s %s re\n",
+			pdfNumber(float64(r.MinX)), pdfNumber(float64(r.MinY)), pdfNumber(float64(r.Width())), pdfNumber(float64(r.Height())))
+	}
+	c.content.WriteString("f Q\n")
+}
+
+// Text writes selectable text, flipped back to upright
+func (c *pdfCanvas) Text(lines []textLine) {
+	for _, line := range lines {
+		font := c.font(line.Font)
+		fmt.Fprintf(&c.content, "BT %s %s %s rg /%s %s Tf 1 0 0 -1 %s %s Tm %s Tj ET\n",
+			pdfColor(line.Color.R), pdfColor(line.Color.G), pdfColor(line.Color.B),
+			font.Name, pdfNumber(line.Size), pdfNumber(line.X), pdfNumber(line.Y), font.encode(line.Text))
+	}
+}
+
+// TemplateImage draws the image of the template, the image is written once
+// and drawn on every page it covers
+func (c *pdfCanvas) TemplateImage(t *Template, r v6.Rect) {
+	if t.Image == nil {
+		return
+	}
+	index := -1
+	for i, im := range c.images {
+		if im.Template == t {
+			index = i
+		}
+	}
+	if index < 0 {
+		index = len(c.images)
+		c.images = append(c.images, &pdfImage{Template: t, Id: writeImage(c.p, t.Image)})
+	}
+	// the image is upside down in the flipped page
+	fmt.Fprintf(&c.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n",
+		pdfNumber(float64(r.Width())), pdfNumber(-float64(r.Height())),
+		pdfNumber(float64(r.MinX)), pdfNumber(float64(r.MaxY)), index+1)
+}
+
+// writeImage writes the image as rgb, the alpha channel becomes a soft mask
+// when the image is not opaque
+func writeImage(p *pdfWriter, img image.Image) int {
+	bounds := img.Bounds()
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	alpha := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			opaque = opaque && c.A == 0xff
+		}
+	}
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8", bounds.Dx(), bounds.Dy())
+	mask := ""
+	if !opaque {
+		id := p.reserve()
+		p.stream(id, dict+" /ColorSpace /DeviceGray", alpha)
+		mask = fmt.Sprintf(" /SMask %d 0 R", id)
+	}
+	id := p.reserve()
+	p.stream(id, dict+" /ColorSpace /DeviceRGB"+mask, rgb)
+	return id
+}
+
+// alpha returns the name of the graphics state with the opacity
+func (c *pdfCanvas) alpha(a uint8) string {
+	name, ok := c.alphas[a]
+	if !ok {
+		name = fmt.Sprintf("GS%d", len(c.alphas)+1)
+		c.alphas[a] = name
+	}
+	return name
+}
+
+func pdfNumber(f float64) string {
+	return svgNumber(f)
+}
+
+func pdfColor(c uint8) string {
+	return pdfNumber(float64(c) / 0xff)
+}
+
+var pdfEscaper = strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", `\r`, "\n", `\n`)
+
+// pdfString writes a text string, as utf-16 if it is not ascii
+func pdfString(s string) string {
+	for _, r := range s {
+		if r >= utf8.RuneSelf {
+			var buffer strings.Builder
+			buffer.WriteString("<feff")
+			for _, u := range utf16.Encode([]rune(s)) {
+				fmt.Fprintf(&buffer, "%04x", u)
+			}
+			buffer.WriteString(">")
+			return buffer.String()
+		}
+	}
+	return "(" + pdfEscaper.Replace(s) + ")"
+}
@@ -0,0 +1,83 @@
+package render
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// readBack parses the written pdf and checks that every object can be read
+func readBack(t *testing.T, data []byte) (*pdfReader, []*pdfPage) {
+	t.Helper()
+	r, err := readPdf(data)
+	if err != nil {
+		t.Fatalf("written pdf can't be read: %v", err)
+	}
+	for id := range r.xref {
+		v, err := r.object(id)
+		if err != nil {
+			t.Fatalf("object %d: %v", id, err)
+		}
+		if s, ok := v.(*pdfStream); ok {
+			if _, err := r.decode(s); err != nil {
+				t.Fatalf("stream %d: %v", id, err)
+			}
+		}
+	}
+	pages, err := r.pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, pages
+}
+
+// nearPoints reports whether the size in points is the size in pixels
+func nearPoints(points, pixels float32) bool {
+	return math.Abs(float64(points)-float64(pixels)*pdfScale) < 0.01
+}
+
+func TestPDFReadBack(t *testing.T) {
+	for _, name := range notebooks {
+		scene := readNotebook(t, name)
+		var out bytes.Buffer
+		if err := PDF(&out, scene, nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		r, pages := readBack(t, out.Bytes())
+		if len(pages) != 1 {
+			t.Fatalf("%s: %d pages", name, len(pages))
+		}
+		frame := PageFrame(scene, &Options{})
+		box := pages[0].MediaBox
+		if !nearPoints(box.Width(), frame.Width()) || !nearPoints(box.Height(), frame.Height()) {
+			t.Errorf("%s: media box %v for the frame %v", name, box, frame)
+		}
+		root := r.dict(r.trailer["Root"])
+		properties := r.dict(root["OCProperties"])
+		if ocgs, _ := properties["OCGs"].(pdfArray); len(ocgs) != len(scene.Layers) {
+			t.Errorf("%s: %d optional content groups, want %d layers", name, len(ocgs), len(scene.Layers))
+		}
+	}
+}
+
+func TestPDFSplit(t *testing.T) {
+	// three device pages high
+	scene := testScene(testLine(v6.ToolFineliner, 100, 100, 100, 3*PageHeight-100))
+	opts := &Options{Split: true}
+	var out bytes.Buffer
+	if err := PDF(&out, scene, opts); err != nil {
+		t.Fatal(err)
+	}
+	_, pages := readBack(t, out.Bytes())
+	frames := SplitPages(opts.frame(scene))
+	if len(pages) != len(frames) || len(pages) < 3 {
+		t.Fatalf("%d pages, want %d", len(pages), len(frames))
+	}
+	for i, page := range pages {
+		if box := page.MediaBox; !nearPoints(box.Width(), frames[i].Width()) || !nearPoints(box.Height(), frames[i].Height()) {
+			t.Errorf("page %d: media box %v for the frame %v", i, box, frames[i])
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+)
+
+// pdfFont is an embedded TrueType font, the text is written as glyph ids
+// and mapped back to unicode so it can be selected and searched
+type pdfFont struct {
+	Font   *textFont
+	Name   string
+	Id     int
+	glyphs map[sfnt.GlyphIndex]rune
+}
+
+// font returns the embedded font, it is written at the end
+func (c *pdfCanvas) font(f *textFont) *pdfFont {
+	for _, font := range c.fonts {
+		if font.Font == f {
+			return font
+		}
+	}
+	font := &pdfFont{
+		Font:   f,
+		Name:   fmt.Sprintf("F%d", len(c.fonts)+1),
+		Id:     c.p.reserve(),
+		glyphs: make(map[sfnt.GlyphIndex]rune),
+	}
+	c.fonts = append(c.fonts, font)
+	return font
+}
+
+// encode returns the text as hex string of glyph ids
+func (f *pdfFont) encode(text string) string {
+	var buffer strings.Builder
+	buffer.WriteByte('<')
+	for _, r := range text {
+		g := f.Font.glyph(r)
+		if _, ok := f.glyphs[g]; !ok {
+			f.glyphs[g] = r
+		}
+		fmt.Fprintf(&buffer, "%04x", uint16(g))
+	}
+	buffer.WriteByte('>')
+	return buffer.String()
+}
+
+// write writes the font objects
+func (f *pdfFont) write(p *pdfWriter) {
+	cidFont := p.reserve()
+	descriptor := p.reserve()
+	file := p.reserve()
+	toUnicode := p.reserve()
+
+	glyphs := make([]sfnt.GlyphIndex, 0, len(f.glyphs))
+	for g := range f.glyphs {
+		glyphs = append(glyphs, g)
+	}
+	sort.Slice(glyphs, func(i, j int) bool {
+		return glyphs[i] < glyphs[j]
+	})
+
+	// the widths and metrics are in 1/1000 of the font size
+	units := 1000 / f.Font.unitsPerEm()
+	var widths strings.Builder
+	for _, g := range glyphs {
+		fmt.Fprintf(&widths, " %d [%s]", g, pdfNumber(f.Font.advance(g)*units))
+	}
+	var b sfnt.Buffer
+	bounds, _ := f.Font.font.Bounds(&b, f.Font.ppem(), font.HintingNone)
+	metrics := f.Font.metrics()
+	toPdf := func(v float64) string {
+		return pdfNumber(v / 64 * units)
+	}
+
+	p.object(f.Id, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		f.Font.Name, cidFont, toUnicode))
+	p.object(cidFont, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s ] >>",
+		f.Font.Name, descriptor, widths.String()))
+	// y goes down in sfnt
+	p.object(descriptor, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%s %s %s %s] /ItalicAngle 0 /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 %d 0 R >>",
+		f.Font.Name,
+		toPdf(float64(bounds.Min.X)), toPdf(float64(-bounds.Max.Y)), toPdf(float64(bounds.Max.X)), toPdf(float64(-bounds.Min.Y)),
+		toPdf(float64(metrics.Ascent)), toPdf(float64(-metrics.Descent)), toPdf(float64(metrics.Ascent)), file))
+	p.stream(file, fmt.Sprintf("/Length1 %d", len(f.Font.Data)), f.Font.Data)
+	p.stream(toUnicode, "", f.toUnicode(glyphs))
+}
+
+// at most 100 entries per block
+const maxCMapEntries = 100
+
+// toUnicode is the cmap from the glyphs to the text
+func (f *pdfFont) toUnicode(glyphs []sfnt.GlyphIndex) []byte {
+	var buffer strings.Builder
+	buffer.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <ffff>\nendcodespacerange\n")
+	for len(glyphs) > 0 {
+		n := len(glyphs)
+		if n > maxCMapEntries {
+			n = maxCMapEntries
+		}
+		fmt.Fprintf(&buffer, "%d beginbfchar\n", n)
+		for _, g := range glyphs[:n] {
+			fmt.Fprintf(&buffer, "<%04x> <", uint16(g))
+			for _, u := range utf16.Encode([]rune{f.glyphs[g]}) {
+				fmt.Fprintf(&buffer, "%04x", u)
+			}
+			buffer.WriteString(">\n")
+		}
+		buffer.WriteString("endbfchar\n")
+		glyphs = glyphs[n:]
+	}
+	buffer.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return []byte(buffer.String())
+}
@@ -0,0 +1,768 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// objects of a pdf file, numbers are float64, strings pdfBytes
+type (
+	pdfName  string
+	pdfBytes []byte
+	pdfArray []interface{}
+	pdfDict  map[pdfName]interface{}
+	pdfRef   struct {
+		Id, Gen int
+	}
+	pdfStream struct {
+		Dict pdfDict
+		Data []byte
+	}
+)
+
+var (
+	ErrPdfEncrypted = errors.New("encrypted pdf files are not supported")
+	errPdfSyntax    = errors.New("pdf syntax error")
+)
+
+// xrefEntry is where an object is, either at an offset of the file or in an
+// object stream
+type xrefEntry struct {
+	Offset   int
+	Gen      int
+	Stream   int
+	Index    int
+	InStream bool
+}
+
+// pdfReader reads the objects of a pdf file as far as needed to add
+// content to its pages
+type pdfReader struct {
+	data    []byte
+	xref    map[int]xrefEntry
+	trailer pdfDict
+	// offset of the last cross reference section
+	startxref int
+	// the last section is a cross reference stream
+	xrefStream bool
+	objects    map[int]interface{}
+}
+
+func readPdf(data []byte) (r *pdfReader, err error) {
+	r = &pdfReader{
+		data:    data,
+		xref:    make(map[int]xrefEntry),
+		objects: make(map[int]interface{}),
+	}
+	tail := data
+	if len(tail) > 1024 {
+		tail = tail[len(tail)-1024:]
+	}
+	i := bytes.LastIndex(tail, []byte("startxref"))
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no startxref", errPdfSyntax)
+	}
+	l := &pdfLexer{data: tail, pos: i + len("startxref")}
+	v, err := l.value()
+	if err != nil {
+		return
+	}
+	start, ok := pdfInt(v)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid startxref", errPdfSyntax)
+	}
+	r.startxref = start
+
+	seen := make(map[int]bool)
+	for offset := start; !seen[offset]; {
+		seen[offset] = true
+		var trailer pdfDict
+		trailer, err = r.readXref(offset)
+		if err != nil {
+			return
+		}
+		if r.trailer == nil {
+			r.trailer = trailer
+			r.xrefStream = !bytes.HasPrefix(data[offset:], []byte("xref"))
+		}
+		// hybrid files have the stream of the newer objects in the trailer
+		if stm, ok := pdfInt(trailer["XRefStm"]); ok && !seen[stm] {
+			seen[stm] = true
+			_, err = r.readXref(stm)
+			if err != nil {
+				return
+			}
+		}
+		prev, ok := pdfInt(trailer["Prev"])
+		if !ok {
+			break
+		}
+		offset = prev
+	}
+	if r.trailer["Encrypt"] != nil {
+		return nil, ErrPdfEncrypted
+	}
+	return
+}
+
+// readXref reads a cross reference section, entries that are already
+// known are newer and kept
+func (r *pdfReader) readXref(offset int) (trailer pdfDict, err error) {
+	if offset < 0 || offset >= len(r.data) {
+		return nil, fmt.Errorf("%w: xref at %d outside of the file", errPdfSyntax, offset)
+	}
+	l := &pdfLexer{data: r.data, pos: offset}
+	if !l.keyword("xref") {
+		return r.readXrefStream(offset)
+	}
+	for {
+		if l.keyword("trailer") {
+			v, err := l.value()
+			if err != nil {
+				return nil, err
+			}
+			trailer, _ = v.(pdfDict)
+			return trailer, nil
+		}
+		var header [2]int
+		for i := range header {
+			v, err := l.value()
+			if err != nil {
+				return nil, err
+			}
+			header[i], _ = pdfInt(v)
+		}
+		for id := header[0]; id < header[0]+header[1]; id++ {
+			var fields [2]int
+			for i := range fields {
+				v, err := l.value()
+				if err != nil {
+					return nil, err
+				}
+				fields[i], _ = pdfInt(v)
+			}
+			l.skipSpace()
+			inUse := l.keyword("n")
+			if !inUse && !l.keyword("f") {
+				return nil, fmt.Errorf("%w: invalid xref entry %d", errPdfSyntax, id)
+			}
+			if _, ok := r.xref[id]; !ok && inUse {
+				r.xref[id] = xrefEntry{Offset: fields[0], Gen: fields[1]}
+			}
+		}
+	}
+}
+
+func (r *pdfReader) readXrefStream(offset int) (trailer pdfDict, err error) {
+	_, v, err := r.parseObject(r.data, offset)
+	if err != nil {
+		return
+	}
+	stream, ok := v.(*pdfStream)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid xref at %d", errPdfSyntax, offset)
+	}
+	data, err := r.decode(stream)
+	if err != nil {
+		return
+	}
+	var w [3]int
+	widths, _ := stream.Dict["W"].(pdfArray)
+	if len(widths) != 3 {
+		return nil, fmt.Errorf("%w: invalid xref stream", errPdfSyntax)
+	}
+	entry := 0
+	for i := range w {
+		w[i], _ = pdfInt(widths[i])
+		// a field larger than an int does not fit any offset
+		if w[i] < 0 || w[i] > 8 {
+			return nil, fmt.Errorf("%w: invalid xref stream", errPdfSyntax)
+		}
+		entry += w[i]
+	}
+	if entry == 0 {
+		return nil, fmt.Errorf("%w: invalid xref stream", errPdfSyntax)
+	}
+	size, _ := pdfInt(stream.Dict["Size"])
+	index := pdfArray{0.0, float64(size)}
+	if i, ok := stream.Dict["Index"].(pdfArray); ok {
+		index = i
+	}
+	field := func(n int) int {
+		value := 0
+		for i := 0; i < n && len(data) > 0; i++ {
+			value = value<<8 | int(data[0])
+			data = data[1:]
+		}
+		return value
+	}
+	// the counts are not trusted, the entries end with the data
+	for i := 0; i+1 < len(index) && len(data) >= entry; i += 2 {
+		start, _ := pdfInt(index[i])
+		count, _ := pdfInt(index[i+1])
+		for id := start; id < start+count && len(data) >= entry; id++ {
+			kind := 1
+			if w[0] > 0 {
+				kind = field(w[0])
+			}
+			a, b := field(w[1]), field(w[2])
+			if _, ok := r.xref[id]; ok {
+				continue
+			}
+			switch kind {
+			case 1:
+				r.xref[id] = xrefEntry{Offset: a, Gen: b}
+			case 2:
+				r.xref[id] = xrefEntry{Stream: a, Index: b, InStream: true}
+			}
+		}
+	}
+	return stream.Dict, nil
+}
+
+// size is the number of objects from the trailer
+func (r *pdfReader) size() int {
+	size, _ := pdfInt(r.trailer["Size"])
+	return size
+}
+
+// object returns the object with the number, nil if there is none
+func (r *pdfReader) object(id int) (v interface{}, err error) {
+	if v, ok := r.objects[id]; ok {
+		return v, nil
+	}
+	entry, ok := r.xref[id]
+	if !ok {
+		return nil, nil
+	}
+	// against loops in broken files
+	r.objects[id] = nil
+	if entry.InStream {
+		v, err = r.streamObject(entry)
+	} else {
+		_, v, err = r.parseObject(r.data, entry.Offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("object %d: %w", id, err)
+	}
+	r.objects[id] = v
+	return
+}
+
+// resolve follows references
+func (r *pdfReader) resolve(v interface{}) (interface{}, error) {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v, nil
+		}
+		var err error
+		v, err = r.object(ref.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: reference loop", errPdfSyntax)
+}
+
+func (r *pdfReader) dict(v interface{}) pdfDict {
+	v, _ = r.resolve(v)
+	switch d := v.(type) {
+	case pdfDict:
+		return d
+	case *pdfStream:
+		return d.Dict
+	}
+	return nil
+}
+
+func (r *pdfReader) streamObject(entry xrefEntry) (v interface{}, err error) {
+	container, err := r.object(entry.Stream)
+	if err != nil {
+		return
+	}
+	stream, ok := container.(*pdfStream)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid object stream %d", errPdfSyntax, entry.Stream)
+	}
+	data, err := r.decode(stream)
+	if err != nil {
+		return
+	}
+	n, _ := pdfInt(stream.Dict["N"])
+	first, _ := pdfInt(stream.Dict["First"])
+	l := &pdfLexer{data: data}
+	for i := 0; i < n; i++ {
+		var offset interface{}
+		// the object number
+		if _, err = l.value(); err != nil {
+			return
+		}
+		if offset, err = l.value(); err != nil {
+			return
+		}
+		if i == entry.Index {
+			o, _ := pdfInt(offset)
+			if first+o < 0 || first+o >= len(data) {
+				return nil, fmt.Errorf("%w: object %d outside of stream %d", errPdfSyntax, entry.Index, entry.Stream)
+			}
+			l = &pdfLexer{data: data, pos: first + o}
+			return l.value()
+		}
+	}
+	return nil, fmt.Errorf("%w: object %d not in stream %d", errPdfSyntax, entry.Index, entry.Stream)
+}
+
+// parseObject parses "id gen obj value endobj" at the offset
+func (r *pdfReader) parseObject(data []byte, offset int) (ref pdfRef, v interface{}, err error) {
+	if offset < 0 || offset >= len(data) {
+		return ref, nil, fmt.Errorf("%w: object at %d outside of the file", errPdfSyntax, offset)
+	}
+	l := &pdfLexer{data: data, pos: offset}
+	var header [2]interface{}
+	for i := range header {
+		if header[i], err = l.value(); err != nil {
+			return
+		}
+	}
+	ref.Id, _ = pdfInt(header[0])
+	ref.Gen, _ = pdfInt(header[1])
+	if !l.keyword("obj") {
+		return ref, nil, fmt.Errorf("%w: no object at %d", errPdfSyntax, offset)
+	}
+	if v, err = l.value(); err != nil {
+		return
+	}
+	dict, ok := v.(pdfDict)
+	if !ok || !l.keyword("stream") {
+		return
+	}
+	// the data starts after the end of the line
+	if l.pos < len(data) && data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(data) && data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+	length := -1
+	if lv, err := r.resolve(dict["Length"]); err == nil {
+		if n, ok := pdfInt(lv); ok {
+			length = n
+		}
+	}
+	end := start + length
+	if length < 0 || end > len(data) || !bytes.HasPrefix(bytes.TrimLeft(data[end:], "\r\n "), []byte("endstream")) {
+		// wrong length, look for the end
+		i := bytes.Index(data[start:], []byte("endstream"))
+		if i < 0 {
+			return ref, nil, fmt.Errorf("%w: unterminated stream at %d", errPdfSyntax, offset)
+		}
+		end = start + len(bytes.TrimRight(data[start:start+i], "\r\n"))
+	}
+	return ref, &pdfStream{Dict: dict, Data: data[start:end]}, nil
+}
+
+// decode returns the data of the stream, only the filters used for the
+// structure of the file are supported
+func (r *pdfReader) decode(s *pdfStream) (data []byte, err error) {
+	data = s.Data
+	filters, _ := r.resolve(s.Dict["Filter"])
+	params, _ := r.resolve(s.Dict["DecodeParms"])
+	if name, ok := filters.(pdfName); ok {
+		filters = pdfArray{name}
+		params = pdfArray{params}
+	}
+	list, _ := filters.(pdfArray)
+	paramList, _ := params.(pdfArray)
+	for i, f := range list {
+		name, _ := f.(pdfName)
+		if name != "FlateDecode" {
+			return nil, fmt.Errorf("unsupported pdf filter: %s", name)
+		}
+		var z io.ReadCloser
+		z, err = zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		data, err = io.ReadAll(z)
+		z.Close()
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return
+		}
+		err = nil
+		if i < len(paramList) {
+			data, err = unpredict(data, r.dict(paramList[i]))
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// unpredict undoes the png predictors
+func unpredict(data []byte, params pdfDict) ([]byte, error) {
+	predictor, _ := pdfInt(params["Predictor"])
+	if predictor < 10 {
+		if predictor > 1 {
+			return nil, fmt.Errorf("unsupported pdf predictor: %d", predictor)
+		}
+		return data, nil
+	}
+	columns := 1
+	if c, ok := pdfInt(params["Columns"]); ok {
+		columns = c
+	}
+	colors := 1
+	if c, ok := pdfInt(params["Colors"]); ok {
+		colors = c
+	}
+	bpc := 8
+	if b, ok := pdfInt(params["BitsPerComponent"]); ok {
+		bpc = b
+	}
+	bpp := (colors*bpc + 7) / 8
+	rowLength := (columns*colors*bpc + 7) / 8
+	var result []byte
+	prev := make([]byte, rowLength)
+	for len(data) >= rowLength+1 {
+		filter, row := data[0], data[1:rowLength+1]
+		data = data[rowLength+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		result = append(result, row...)
+		prev = row
+	}
+	return result, nil
+}
+
+func paeth(a, b, c byte) byte {
+	abs := func(i int) int {
+		if i < 0 {
+			return -i
+		}
+		return i
+	}
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func pdfInt(v interface{}) (int, bool) {
+	f, ok := v.(float64)
+	return int(f), ok
+}
+
+func pdfFloat(v interface{}) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
+
+// pdfLexer parses objects from the data
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPdfSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPdfDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPdfSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// regular reads a run of regular characters
+func (l *pdfLexer) regular() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPdfSpace(l.data[l.pos]) && !isPdfDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// keyword consumes the keyword if it is next
+func (l *pdfLexer) keyword(word string) bool {
+	l.skipSpace()
+	start := l.pos
+	if l.regular() == word {
+		return true
+	}
+	l.pos = start
+	return false
+}
+
+func (l *pdfLexer) value() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	switch c := l.data[l.pos]; c {
+	case '/':
+		l.pos++
+		return l.name(), nil
+	case '(':
+		l.pos++
+		return l.literal()
+	case '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return l.dict()
+		}
+		l.pos++
+		return l.hex()
+	case '[':
+		l.pos++
+		var array pdfArray
+		for {
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == ']' {
+				l.pos++
+				return array, nil
+			}
+			v, err := l.value()
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, v)
+		}
+	case ']', '>', ')', '{', '}':
+		return nil, fmt.Errorf("%w: unexpected %q at %d", errPdfSyntax, c, l.pos)
+	}
+
+	word := l.regular()
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unexpected %q at %d", errPdfSyntax, word, l.pos)
+	}
+	// a reference is two integers and R
+	if f == float64(int(f)) && !strings.Contains(word, ".") {
+		save := l.pos
+		l.skipSpace()
+		gen := l.regular()
+		if g, err := strconv.Atoi(gen); err == nil && l.keyword("R") {
+			return pdfRef{Id: int(f), Gen: g}, nil
+		}
+		l.pos = save
+	}
+	return f, nil
+}
+
+func (l *pdfLexer) name() pdfName {
+	word := l.regular()
+	if !strings.Contains(word, "#") {
+		return pdfName(word)
+	}
+	var name []byte
+	for i := 0; i < len(word); i++ {
+		if word[i] == '#' && i+2 < len(word) {
+			if b, err := strconv.ParseUint(word[i+1:i+3], 16, 8); err == nil {
+				name = append(name, byte(b))
+				i += 2
+				continue
+			}
+		}
+		name = append(name, word[i])
+	}
+	return pdfName(name)
+}
+
+func (l *pdfLexer) dict() (interface{}, error) {
+	dict := make(pdfDict)
+	for {
+		l.skipSpace()
+		if l.pos+1 < len(l.data) && l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return dict, nil
+		}
+		key, err := l.value()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			return nil, fmt.Errorf("%w: dictionary key %v at %d", errPdfSyntax, key, l.pos)
+		}
+		v, err := l.value()
+		if err != nil {
+			return nil, err
+		}
+		dict[name] = v
+	}
+}
+
+func (l *pdfLexer) literal() (interface{}, error) {
+	var s []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfBytes(s), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				break
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					n := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(n)
+				}
+			}
+		}
+		s = append(s, c)
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+func (l *pdfLexer) hex() (interface{}, error) {
+	var digits []byte
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			s := make([]byte, len(digits)/2)
+			for i := range s {
+				b, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+				s[i] = byte(b)
+			}
+			return pdfBytes(s), nil
+		}
+		if !isPdfSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+// formatPdf writes a direct object, streams can only be referenced
+func formatPdf(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case pdfName:
+		var name strings.Builder
+		name.WriteByte('/')
+		for _, c := range []byte(v) {
+			if c <= ' ' || c >= 0x7f || c == '#' || isPdfDelimiter(c) {
+				fmt.Fprintf(&name, "#%02x", c)
+				continue
+			}
+			name.WriteByte(c)
+		}
+		return name.String()
+	case pdfBytes:
+		return fmt.Sprintf("<%x>", []byte(v))
+	case pdfRef:
+		return fmt.Sprintf("%d %d R", v.Id, v.Gen)
+	case pdfArray:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatPdf(item)
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	case pdfDict:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, string(key))
+		}
+		sort.Strings(keys)
+		var dict strings.Builder
+		dict.WriteString("<<")
+		for _, key := range keys {
+			fmt.Fprintf(&dict, " %s %s", formatPdf(pdfName(key)), formatPdf(v[pdfName(key)]))
+		}
+		dict.WriteString(" >>")
+		return dict.String()
+	}
+	return "null"
+}
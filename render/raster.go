@@ -0,0 +1,321 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+
+	v6 "github.com/ddvk/reader/v6"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/vector"
+)
+
+const defaultQuality = 90
+
+// thinner lines disappear when anti-aliased
+const minRasterWidth = 1
+
+type rasterCanvas struct {
+	img   *image.RGBA
+	scale float64
+	// position of the page origin in the image
+	originX float64
+	originY float64
+	r       *vector.Rasterizer
+	// grain of the paper for textured tools, nil draws them plain
+	grain *grain
+}
+
+// Raster renders the scene into an image with the resolution from the options
+func Raster(scene *v6.Scene, opts *Options) image.Image {
+	if opts == nil {
+		opts = &Options{}
+	}
+	scene, opts = opts.selected(scene)
+	if opts.Display != nil {
+		return opts.Display.render(scene, opts)
+	}
+	frame := opts.frame(scene)
+	c := newRasterCanvas(frame, opts)
+	drawScene(c, scene, frame, opts)
+	return c.img
+}
+
+// newRasterCanvas returns an image of the frame filled with the background
+func newRasterCanvas(frame v6.Rect, opts *Options) *rasterCanvas {
+	scale := opts.scale()
+	width := pixels(float64(frame.Width()) * scale)
+	height := pixels(float64(frame.Height()) * scale)
+	c := &rasterCanvas{
+		img:     image.NewRGBA(image.Rect(0, 0, width, height)),
+		scale:   scale,
+		originX: -float64(frame.MinX) * scale,
+		originY: -float64(frame.MinY) * scale,
+		r:       vector.NewRasterizer(0, 0),
+		grain:   newGrain(opts),
+	}
+	draw.Draw(c.img, c.img.Bounds(), image.NewUniform(opts.palette().Background), image.Point{}, draw.Src)
+	return c
+}
+
+// pixels rounds a size up, rounding errors don't add a pixel
+func pixels(size float64) int {
+	return int(math.Ceil(size - 1e-6))
+}
+
+// PNG writes the rendered scene as png
+func PNG(w io.Writer, scene *v6.Scene, opts *Options) error {
+	return png.Encode(w, Raster(scene, opts))
+}
+
+// JPEG writes the rendered scene as jpeg
+func JPEG(w io.Writer, scene *v6.Scene, opts *Options) error {
+	quality := defaultQuality
+	if opts != nil && opts.Quality > 0 {
+		quality = opts.Quality
+	}
+	return jpeg.Encode(w, Raster(scene, opts), &jpeg.Options{Quality: quality})
+}
+
+func (c *rasterCanvas) BeginLayer(index int, layer *v6.Layer) {
+}
+
+func (c *rasterCanvas) EndLayer() {
+}
+
+// toImage converts page to image coordinates
+func (c *rasterCanvas) toImage(x, y float64) (float64, float64) {
+	return x*c.scale + c.originX, y*c.scale + c.originY
+}
+
+// Stroke fills the outline of the line, a disc at every point joined by
+// quads so the width can change smoothly along the line. Every run with
+// the same opacity is filled on its own. Pencils and the paintbrush take
+// the grain of the paper when it is set, calligraphy is filled with the
+// outline of the nib.
+func (c *rasterCanvas) Stroke(s *stroke) {
+	if c.grain != nil && s.Item != nil {
+		if t, ok := teeth[s.Item.Line.Value.Tool.Base()]; ok {
+			c.texturedStroke(s, t)
+			return
+		}
+	}
+	if s.isCalligraphy() {
+		c.nibStroke(s)
+		return
+	}
+	for _, run := range s.runs(func(a, b strokePoint) bool {
+		return a.Opacity == b.Opacity
+	}) {
+		points := make([]strokePoint, len(run))
+		bounds := image.Rectangle{}
+		for i, p := range run {
+			x, y := c.toImage(p.X, p.Y)
+			radius := math.Max(p.Width*c.scale, minRasterWidth) / 2
+			points[i] = strokePoint{X: x, Y: y, Width: radius}
+			bounds = bounds.Union(image.Rect(
+				int(math.Floor(x-radius)), int(math.Floor(y-radius)),
+				int(math.Ceil(x+radius))+1, int(math.Ceil(y+radius))+1))
+		}
+		bounds = bounds.Intersect(c.img.Bounds())
+		if bounds.Empty() {
+			continue
+		}
+		col := s.Color
+		col.A = uint8(math.Round(run[len(run)-1].Opacity * 0xff))
+		c.fill(bounds, points, col)
+	}
+}
+
+// nibStroke fills the outline of the nib swept along the line for every
+// run with the same opacity
+func (c *rasterCanvas) nibStroke(s *stroke) {
+	for _, run := range s.runs(func(a, b strokePoint) bool {
+		return a.Opacity == b.Opacity
+	}) {
+		points := make([]strokePoint, len(run))
+		for i, p := range run {
+			x, y := c.toImage(p.X, p.Y)
+			points[i] = p
+			points[i].X, points[i].Y = x, y
+			points[i].Width = math.Max(p.Width*c.scale, minRasterWidth)
+		}
+		var polygons [][2][]float64
+		bounds := image.Rectangle{}
+		nibPolygons(points, func(xs, ys []float64) {
+			polygons = append(polygons, [2][]float64{xs, ys})
+			for i := range xs {
+				bounds = bounds.Union(image.Rect(
+					int(math.Floor(xs[i])), int(math.Floor(ys[i])),
+					int(math.Ceil(xs[i]))+1, int(math.Ceil(ys[i]))+1))
+			}
+		})
+		bounds = bounds.Intersect(c.img.Bounds())
+		if bounds.Empty() {
+			continue
+		}
+		c.r.Reset(bounds.Dx(), bounds.Dy())
+		ox, oy := float64(bounds.Min.X), float64(bounds.Min.Y)
+		for _, polygon := range polygons {
+			xs, ys := polygon[0], polygon[1]
+			for i := range xs {
+				xs[i] -= ox
+				ys[i] -= oy
+			}
+			addPolygon(c.r, xs, ys)
+		}
+		col := s.Color
+		col.A = uint8(math.Round(run[len(run)-1].Opacity * 0xff))
+		c.r.Draw(c.img, bounds, image.NewUniform(col), image.Point{})
+	}
+}
+
+// strokeBounds is the area of the image the stroke draws on
+func (c *rasterCanvas) strokeBounds(s *stroke) (bounds image.Rectangle) {
+	for _, p := range s.Points {
+		x, y := c.toImage(p.X, p.Y)
+		radius := math.Max(p.Width*c.scale, minRasterWidth) / 2
+		if s.isCalligraphy() {
+			// the corners of the nib
+			radius *= math.Hypot(1, nibThickness)
+		}
+		bounds = bounds.Union(image.Rect(
+			int(math.Floor(x-radius)), int(math.Floor(y-radius)),
+			int(math.Ceil(x+radius))+1, int(math.Ceil(y+radius))+1))
+	}
+	return bounds.Intersect(c.img.Bounds())
+}
+
+// Highlight fills the rectangles in one pass, so overlapping rectangles
+// are not darker
+func (c *rasterCanvas) Highlight(h *highlight) {
+	bounds := image.Rectangle{}
+	rects := make([]v6.Rect, len(h.Rects))
+	for i, r := range h.Rects {
+		x0, y0 := c.toImage(float64(r.MinX), float64(r.MinY))
+		x1, y1 := c.toImage(float64(r.MaxX), float64(r.MaxY))
+		rects[i] = v6.Rect{MinX: float32(x0), MinY: float32(y0), MaxX: float32(x1), MaxY: float32(y1)}
+		bounds = bounds.Union(image.Rect(
+			int(math.Floor(x0)), int(math.Floor(y0)), int(math.Ceil(x1)), int(math.Ceil(y1))))
+	}
+	bounds = bounds.Intersect(c.img.Bounds())
+	if bounds.Empty() {
+		return
+	}
+	c.r.Reset(bounds.Dx(), bounds.Dy())
+	ox, oy := float64(bounds.Min.X), float64(bounds.Min.Y)
+	for _, r := range rects {
+		x0, y0 := float64(r.MinX)-ox, float64(r.MinY)-oy
+		x1, y1 := float64(r.MaxX)-ox, float64(r.MaxY)-oy
+		addPolygon(c.r, []float64{x0, x1, x1, x0}, []float64{y0, y0, y1, y1})
+	}
+	c.r.Draw(c.img, bounds, image.NewUniform(h.Color), image.Point{})
+}
+
+// Text fills the outlines of the glyphs
+func (c *rasterCanvas) Text(lines []textLine) {
+	for _, line := range lines {
+		size := line.Size * c.scale
+		x, y := c.toImage(line.X, line.Y)
+		width := line.Font.width(line.Text, size)
+		bounds := image.Rect(
+			int(math.Floor(x)), int(math.Floor(y-size*1.2)),
+			int(math.Ceil(x+width))+1, int(math.Ceil(y+size*0.5))+1).Intersect(c.img.Bounds())
+		if bounds.Empty() {
+			continue
+		}
+		c.r.Reset(bounds.Dx(), bounds.Dy())
+		x -= float64(bounds.Min.X)
+		y -= float64(bounds.Min.Y)
+		for _, r := range line.Text {
+			g := line.Font.glyph(r)
+			line.Font.outline(c.r, g, size, x, y)
+			x += line.Font.advance(g) * size / line.Font.unitsPerEm()
+		}
+		c.r.Draw(c.img, bounds, image.NewUniform(line.Color), image.Point{})
+	}
+}
+
+// TemplateImage scales the image of the template into the rectangle
+func (c *rasterCanvas) TemplateImage(t *Template, r v6.Rect) {
+	if t.Image == nil {
+		return
+	}
+	x0, y0 := c.toImage(float64(r.MinX), float64(r.MinY))
+	x1, y1 := c.toImage(float64(r.MaxX), float64(r.MaxY))
+	bounds := image.Rect(int(math.Round(x0)), int(math.Round(y0)), int(math.Round(x1)), int(math.Round(y1)))
+	xdraw.ApproxBiLinear.Scale(c.img, bounds, t.Image, t.Image.Bounds(), draw.Over, nil)
+}
+
+// fill rasterizes the outline of the points, Width is the radius
+func (c *rasterCanvas) fill(bounds image.Rectangle, points []strokePoint, col color.Color) {
+	c.outline(bounds, points)
+	c.r.Draw(c.img, bounds, image.NewUniform(col), image.Point{})
+}
+
+// outline adds a disc at every point and the quads between them to the
+// rasterizer, Width is the radius
+func (c *rasterCanvas) outline(bounds image.Rectangle, points []strokePoint) {
+	c.r.Reset(bounds.Dx(), bounds.Dy())
+	ox, oy := float64(bounds.Min.X), float64(bounds.Min.Y)
+	for i, p := range points {
+		addDisc(c.r, p.X-ox, p.Y-oy, p.Width)
+		if i > 0 {
+			prev := points[i-1]
+			addSegment(c.r, prev.X-ox, prev.Y-oy, prev.Width, p.X-ox, p.Y-oy, p.Width)
+		}
+	}
+}
+
+// the rasterizer adds up overlapping shapes, they all have to be drawn in
+// the same direction so they don't cancel out
+func addPolygon(r *vector.Rasterizer, xs, ys []float64) {
+	area := 0.0
+	for i := range xs {
+		j := (i + 1) % len(xs)
+		area += xs[i]*ys[j] - xs[j]*ys[i]
+	}
+	if area < 0 {
+		for i, j := 0, len(xs)-1; i < j; i, j = i+1, j-1 {
+			xs[i], xs[j] = xs[j], xs[i]
+			ys[i], ys[j] = ys[j], ys[i]
+		}
+	}
+	r.MoveTo(float32(xs[0]), float32(ys[0]))
+	for i := 1; i < len(xs); i++ {
+		r.LineTo(float32(xs[i]), float32(ys[i]))
+	}
+	r.ClosePath()
+}
+
+func addDisc(r *vector.Rasterizer, x, y, radius float64) {
+	n := int(math.Ceil(radius * 2))
+	if n < 8 {
+		n = 8
+	} else if n > 64 {
+		n = 64
+	}
+	xs := make([]float64, n)
+	ys := make([]float64, n)
+	for i := 0; i < n; i++ {
+		sin, cos := math.Sincos(float64(i) * 2 * math.Pi / float64(n))
+		xs[i] = x + cos*radius
+		ys[i] = y + sin*radius
+	}
+	addPolygon(r, xs, ys)
+}
+
+func addSegment(r *vector.Rasterizer, x0, y0, r0, x1, y1, r1 float64) {
+	length := math.Hypot(x1-x0, y1-y0)
+	if length == 0 {
+		return
+	}
+	nx, ny := -(y1-y0)/length, (x1-x0)/length
+	addPolygon(r,
+		[]float64{x0 + nx*r0, x1 + nx*r1, x1 - nx*r1, x0 - nx*r0},
+		[]float64{y0 + ny*r0, y1 + ny*r1, y1 - ny*r1, y0 - ny*r0})
+}
@@ -0,0 +1,80 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// isInk reports whether the color is dark, the lines are black
+func isInk(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r < 0x8000 && g < 0x8000 && b < 0x8000
+}
+
+func TestRasterSize(t *testing.T) {
+	scene := testScene(testLine(v6.ToolFineliner, 100, 100, 500, 100))
+	tests := []struct {
+		dpi           float64
+		width, height int
+	}{
+		{0, PageWidth, PageHeight},
+		{DPI, PageWidth, PageHeight},
+		{DPI / 2, PageWidth / 2, PageHeight / 2},
+		{DPI * 2, PageWidth * 2, PageHeight * 2},
+	}
+	for _, test := range tests {
+		bounds := Raster(scene, &Options{DPI: test.dpi}).Bounds()
+		if bounds.Dx() != test.width || bounds.Dy() != test.height {
+			t.Errorf("dpi %v: %dx%d, want %dx%d", test.dpi, bounds.Dx(), bounds.Dy(), test.width, test.height)
+		}
+	}
+}
+
+func TestRasterFrame(t *testing.T) {
+	// the frame starts left of the page, the line is at 10 pixels
+	scene := testScene(testLine(v6.ToolFineliner, -90, 50, -90, 150))
+	img := Raster(scene, &Options{Frame: &v6.Rect{MinX: -100, MinY: 0, MaxX: 0, MaxY: 200}, DPI: DPI})
+	if bounds := img.Bounds(); bounds != image.Rect(0, 0, 100, 200) {
+		t.Fatalf("bounds %v", bounds)
+	}
+	if !isInk(img.At(10, 100)) {
+		t.Error("no ink on the line")
+	}
+	for _, p := range []image.Point{{50, 100}, {10, 20}, {10, 180}} {
+		if isInk(img.At(p.X, p.Y)) {
+			t.Errorf("ink at %v", p)
+		}
+	}
+}
+
+func TestRasterEncode(t *testing.T) {
+	scene := readNotebook(t, "migration_v6.rm")
+	opts := &Options{DPI: DPI / 4}
+	var buffer bytes.Buffer
+	for _, format := range []struct {
+		name   string
+		encode func(*bytes.Buffer) error
+		decode func(*bytes.Buffer) (image.Image, error)
+	}{
+		{"png", func(b *bytes.Buffer) error { return PNG(b, scene, opts) }, func(b *bytes.Buffer) (image.Image, error) { return png.Decode(b) }},
+		{"jpeg", func(b *bytes.Buffer) error { return JPEG(b, scene, opts) }, func(b *bytes.Buffer) (image.Image, error) { return jpeg.Decode(b) }},
+	} {
+		buffer.Reset()
+		if err := format.encode(&buffer); err != nil {
+			t.Fatalf("%s: %v", format.name, err)
+		}
+		img, err := format.decode(&buffer)
+		if err != nil {
+			t.Fatalf("%s: %v", format.name, err)
+		}
+		if want := Raster(scene, opts).Bounds(); img.Bounds() != want {
+			t.Errorf("%s: bounds %v, want %v", format.name, img.Bounds(), want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package render
+
+import (
+	"image/color"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// page geometry of the device in pixels, x = 0 is the center of the page
+const (
+	PageWidth  = 1404
+	PageHeight = 1872
+	DPI        = 226
+)
+
+// Options are shared by all renderers
+type Options struct {
+	// HiddenLayers draws the layers that are hidden on the device
+	HiddenLayers bool
+	// DPI is the resolution of raster output, the device has 226
+	DPI float64
+	// Display shows the raster output as the e-paper panel does, at the
+	// size of the panel. The frame is the page of the device when not set.
+	Display *Display
+	// Quality of jpeg output, 1 to 100
+	Quality int
+	// Palette the colors to draw with, DefaultPalette when nil
+	Palette *Palette
+	// Template is drawn beneath all layers, nil for a blank page
+	Template *Template
+	// Frame is the area of the page that is drawn, PageFrame when nil
+	Frame *v6.Rect
+	// Crop draws only the content with Margin around it when Frame is not
+	// set, see CropFrame
+	Crop   bool
+	Margin float64
+	// Split the frame into pages of the device size, see SplitPages. Only
+	// the pdf output has several pages.
+	Split bool
+	// Texture draws pencils and the paintbrush with the grain of the
+	// paper, raster output only. The same Seed draws the same grain.
+	Texture bool
+	Seed    int64
+	// Filter selects the content that is drawn, all of it when nil
+	Filter *Filter
+	// Debug draws the lines in the colors of their authors, with their
+	// bounding boxes, ids and the anchors of the groups on top
+	Debug bool
+
+	// filtered is set once the filter is applied
+	filtered bool
+}
+
+func (o *Options) palette() *Palette {
+	if o.Palette == nil {
+		return DefaultPalette
+	}
+	return o.Palette
+}
+
+func (o *Options) scale() float64 {
+	if o.DPI <= 0 {
+		return 1
+	}
+	return o.DPI / DPI
+}
+
+// strokePoint is a point of a line as it is drawn
+type strokePoint struct {
+	X, Y    float64
+	Width   float64
+	Opacity float64
+	// Pressure 0 to 1, Speed and Direction in radians as the pen moved
+	Pressure  float64
+	Speed     float64
+	Direction float64
+}
+
+// stroke is a line ready for drawing
+type stroke struct {
+	Item   *v6.LineItem
+	Color  color.NRGBA
+	Points []strokePoint
+}
+
+// highlight is a text highlight ready for drawing
+type highlight struct {
+	Item  *v6.GlyphRange
+	Color color.NRGBA
+	Rects []v6.Rect
+}
+
+// canvas is implemented by the output formats
+type canvas interface {
+	BeginLayer(index int, layer *v6.Layer)
+	EndLayer()
+	Stroke(s *stroke)
+	Highlight(h *highlight)
+	Text(lines []textLine)
+	// TemplateImage draws the image of the template into the rectangle
+	TemplateImage(t *Template, r v6.Rect)
+}
+
+// drawScene walks the scene and draws it on the canvas, the template
+// first and the highlights of a layer under its strokes
+func drawScene(c canvas, scene *v6.Scene, frame v6.Rect, opts *Options) {
+	if opts.Template != nil {
+		drawTemplate(c, opts.Template, frame, opts.palette())
+	}
+	if scene.Text != nil {
+		c.Text(layoutText(scene.Text, opts.palette()))
+	}
+	var authors map[v6.AuthorId]color.NRGBA
+	if opts.Debug {
+		authors = debugAuthors(scene)
+	}
+	for i, layer := range scene.Layers {
+		if !layer.IsVisible && !opts.HiddenLayers {
+			continue
+		}
+		c.BeginLayer(i, layer)
+		for _, item := range layer.Highlights {
+			h := newHighlight(item, opts.palette())
+			if h != nil {
+				c.Highlight(h)
+			}
+		}
+		for _, line := range layer.Lines {
+			s := newStroke(line, opts.palette())
+			if s == nil {
+				continue
+			}
+			if opts.Debug {
+				s.Color = authors[line.Id.Author()]
+			}
+			c.Stroke(s)
+		}
+		c.EndLayer()
+	}
+	if opts.Debug {
+		drawDebug(c, scene, frame, opts, authors)
+	}
+}
+
+// newStroke applies the brush of the tool, the alpha of the color is
+// moved to the opacity of the points
+func newStroke(item *v6.LineItem, palette *Palette) *stroke {
+	line := &item.Line.Value
+	b, ok := brushFor(line.Tool)
+	if len(line.Points) == 0 || !ok {
+		return nil
+	}
+	s := &stroke{
+		Item:   item,
+		Color:  palette.LineColor(line),
+		Points: make([]strokePoint, len(line.Points)),
+	}
+	alpha := float64(s.Color.A) / 0xff
+	s.Color.A = 0xff
+	for i, p := range line.Points {
+		s.Points[i] = strokePoint{
+			X:         float64(p.X),
+			Y:         float64(p.Y),
+			Width:     b.Width(line, p),
+			Opacity:   quantize(b.Opacity(p) * alpha),
+			Pressure:  pressure(p),
+			Speed:     speed(p),
+			Direction: direction(p),
+		}
+	}
+	return s
+}
+
+// highlights are see through so the text stays readable
+const highlightOpacity = 0.4
+
+func newHighlight(item *v6.GlyphRange, palette *Palette) *highlight {
+	if len(item.Rectangles) == 0 {
+		return nil
+	}
+	h := &highlight{
+		Item:  item,
+		Color: palette.Color(item.Color),
+	}
+	h.Color.A = uint8(highlightOpacity * 0xff)
+	for _, r := range item.Rectangles {
+		h.Rects = append(h.Rects, v6.Rect{
+			MinX: float32(r.Min.X),
+			MinY: float32(r.Min.Y),
+			MaxX: float32(r.Max.X),
+			MaxY: float32(r.Max.Y),
+		})
+	}
+	return h
+}
+
+// runs splits the points where same is false for two neighbours, the
+// runs share their end points. A single point becomes a run of two.
+func (s *stroke) runs(same func(a, b strokePoint) bool) (runs [][]strokePoint) {
+	points := s.Points
+	if len(points) == 1 {
+		points = append(points, points[0])
+	}
+	start := 0
+	for i := 1; i < len(points); i++ {
+		if i < len(points)-1 && same(points[i], points[i+1]) {
+			continue
+		}
+		runs = append(runs, points[start:i+1])
+		start = i
+	}
+	return
+}
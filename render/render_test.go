@@ -0,0 +1,46 @@
+package render
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// notebooks are the v6 files the tests draw
+var notebooks = []string{
+	"migration_v6.rm",
+	"v6_text.rm",
+}
+
+func readNotebook(t *testing.T, name string) *v6.Scene {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "notebooks", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scene, err := v6.ReadScene(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return &scene
+}
+
+// testLine is a line of the tool through the points, x and y in turn
+func testLine(tool v6.Tool, coords ...float32) *v6.LineItem {
+	line := &v6.LineItem{}
+	line.Line.Value.Tool = tool
+	line.Line.Value.ThicknessScale = 2
+	for i := 0; i+1 < len(coords); i += 2 {
+		line.Line.Value.Points = append(line.Line.Value.Points,
+			&v6.PenPoint{X: coords[i], Y: coords[i+1], Width: 16, Pressure: 200})
+	}
+	line.Line.Value.UpdateBoundingRect()
+	return line
+}
+
+func testScene(lines ...*v6.LineItem) *v6.Scene {
+	return &v6.Scene{Layers: []*v6.Layer{{Name: "Layer 1", IsVisible: true, Lines: lines}}}
+}
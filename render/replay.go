@@ -0,0 +1,186 @@
+package render
+
+import (
+	"bufio"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// ReplayOptions is the timing of a replay. The file has no times, the lines
+// are drawn one after the other at the same speed.
+type ReplayOptions struct {
+	// PointDuration the time to draw a point of a line
+	PointDuration time.Duration
+	// Pause between two lines
+	Pause time.Duration
+	// FrameRate of gif output, in frames per second
+	FrameRate float64
+	// Hold the last frame of a gif before it starts again
+	Hold time.Duration
+}
+
+// DefaultReplay draws a little faster than writing
+var DefaultReplay = &ReplayOptions{
+	PointDuration: 8 * time.Millisecond,
+	Pause:         100 * time.Millisecond,
+	FrameRate:     10,
+	Hold:          3 * time.Second,
+}
+
+// withDefaults takes the durations and the frame rate that are not set
+// from DefaultReplay
+func (r *ReplayOptions) withDefaults() *ReplayOptions {
+	if r == nil {
+		return DefaultReplay
+	}
+	result := *r
+	if result.PointDuration <= 0 {
+		result.PointDuration = DefaultReplay.PointDuration
+	}
+	if result.FrameRate <= 0 {
+		result.FrameRate = DefaultReplay.FrameRate
+	}
+	return &result
+}
+
+// replayStroke is a stroke with the time it starts, in seconds
+type replayStroke struct {
+	*stroke
+	Start float64
+}
+
+func (s *replayStroke) end(pointDuration float64) float64 {
+	return s.Start + math.Max(float64(len(s.Points)-1), 1)*pointDuration
+}
+
+// replayOrder returns the strokes in the order they were written, by the
+// timestamp of the line and then by the id of the item
+func replayOrder(scene *v6.Scene, opts *Options, replay *ReplayOptions) (strokes []replayStroke) {
+	for _, layer := range scene.Layers {
+		if !layer.IsVisible && !opts.HiddenLayers {
+			continue
+		}
+		for _, line := range layer.Lines {
+			if s := newStroke(line, opts.palette()); s != nil {
+				strokes = append(strokes, replayStroke{stroke: s})
+			}
+		}
+	}
+	sort.SliceStable(strokes, func(i, j int) bool {
+		a, b := strokes[i].Item, strokes[j].Item
+		if a.Line.Timestamp != b.Line.Timestamp {
+			return a.Line.Timestamp.Less(b.Line.Timestamp)
+		}
+		return a.Id.Less(b.Id)
+	})
+	pointDuration := replay.PointDuration.Seconds()
+	start := 0.0
+	for i := range strokes {
+		strokes[i].Start = start
+		start = strokes[i].end(pointDuration) + replay.Pause.Seconds()
+	}
+	return
+}
+
+// AnimatedSVG writes an svg that draws the lines in the order they were
+// written. The lines keep their layers, only the time they appear changes.
+func AnimatedSVG(w io.Writer, scene *v6.Scene, opts *Options, replay *ReplayOptions) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	scene, opts = opts.selected(scene)
+	replay = replay.withDefaults()
+	c := &svgCanvas{
+		w:             bufio.NewWriter(w),
+		starts:        make(map[*v6.LineItem]float64),
+		pointDuration: replay.PointDuration.Seconds(),
+	}
+	for _, s := range replayOrder(scene, opts, replay) {
+		c.starts[s.Item] = s.Start
+	}
+	return c.write(scene, opts)
+}
+
+// withoutLines draws everything but the lines of the scene, the template
+// is kept
+type withoutLines struct {
+	canvas
+}
+
+func (c withoutLines) Stroke(s *stroke) {
+	if s.Item == nil {
+		c.canvas.Stroke(s)
+	}
+}
+
+// GIF writes an animated gif that draws the lines in the order they were
+// written. Every frame only holds the part of the page that changed.
+func GIF(w io.Writer, scene *v6.Scene, opts *Options, replay *ReplayOptions) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	scene, opts = opts.selected(scene)
+	replay = replay.withDefaults()
+	frame := opts.frame(scene)
+	c := newRasterCanvas(frame, opts)
+	drawScene(withoutLines{c}, scene, frame, opts)
+	strokes := replayOrder(scene, opts, replay)
+
+	pointDuration := replay.PointDuration.Seconds()
+	interval := 1 / replay.FrameRate
+	delay := int(math.Round(interval * 100))
+	result := &gif.GIF{}
+	addFrame := func(img *image.RGBA, bounds image.Rectangle) {
+		if bounds.Empty() && len(result.Image) > 0 {
+			result.Delay[len(result.Delay)-1] += delay
+			return
+		}
+		paletted := image.NewPaletted(bounds, palette.Plan9)
+		draw.Draw(paletted, bounds, img, bounds.Min, draw.Src)
+		result.Image = append(result.Image, paletted)
+		result.Delay = append(result.Delay, delay)
+		result.Disposal = append(result.Disposal, gif.DisposalNone)
+	}
+
+	// the first frame is the whole page
+	dirty := c.img.Bounds()
+	next := 0
+	for t := 0.0; ; t += interval {
+		for next < len(strokes) && strokes[next].end(pointDuration) <= t {
+			dirty = dirty.Union(c.strokeBounds(strokes[next].stroke))
+			c.Stroke(strokes[next].stroke)
+			next++
+		}
+		if next == len(strokes) {
+			addFrame(c.img, dirty)
+			break
+		}
+		s := strokes[next]
+		if s.Start >= t {
+			addFrame(c.img, dirty)
+			dirty = image.Rectangle{}
+			continue
+		}
+		// the line that is being drawn goes on a copy of the changed area
+		partial := *s.stroke
+		partial.Points = s.Points[:int((t-s.Start)/pointDuration)+1]
+		dirty = dirty.Union(c.strokeBounds(&partial))
+		img := image.NewRGBA(dirty)
+		draw.Draw(img, dirty, c.img, dirty.Min, draw.Src)
+		drawing := *c
+		drawing.img = img
+		drawing.Stroke(&partial)
+		addFrame(img, dirty)
+		dirty = image.Rectangle{}
+	}
+	result.Delay[len(result.Delay)-1] += int(replay.Hold / (10 * time.Millisecond))
+	return gif.EncodeAll(w, result)
+}
@@ -0,0 +1,86 @@
+package render
+
+import (
+	"bytes"
+	"image/gif"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// replayScene has lines written in another order than they are stored
+func replayScene() (*v6.Scene, []*v6.LineItem) {
+	line := func(timestamp, id uint64, coords ...float32) *v6.LineItem {
+		l := testLine(v6.ToolFineliner, coords...)
+		l.Line.Timestamp = v6.NewCrdtId(1, timestamp)
+		l.Id = v6.NewCrdtId(1, id)
+		return l
+	}
+	last := line(5, 1, 10, 10, 20, 10, 30, 10)
+	second := line(3, 9, 10, 20, 20, 20)
+	first := line(3, 2, 10, 30, 20, 30)
+	hidden := line(1, 3, 10, 40, 20, 40)
+	eraser := line(2, 4, 10, 50, 20, 50)
+	eraser.Line.Value.Tool = v6.ToolEraser
+	scene := testScene(last, second, eraser)
+	scene.Layers = append(scene.Layers,
+		&v6.Layer{IsVisible: true, Lines: []*v6.LineItem{first}},
+		&v6.Layer{Lines: []*v6.LineItem{hidden}})
+	return scene, []*v6.LineItem{first, second, last}
+}
+
+func TestReplayOrder(t *testing.T) {
+	scene, want := replayScene()
+	replay := &ReplayOptions{PointDuration: 10 * time.Millisecond, Pause: 100 * time.Millisecond}
+	strokes := replayOrder(scene, &Options{}, replay)
+	if len(strokes) != len(want) {
+		t.Fatalf("%d strokes, want %d", len(strokes), len(want))
+	}
+	start := 0.0
+	for i, s := range strokes {
+		if s.Item != want[i] {
+			t.Errorf("stroke %d is %v, want %v", i, s.Item.Id, want[i].Id)
+		}
+		if math.Abs(s.Start-start) > 1e-9 {
+			t.Errorf("stroke %d starts at %v, want %v", i, s.Start, start)
+		}
+		start += float64(len(s.Points)-1)*0.01 + 0.1
+	}
+
+	// hidden layers are replayed when they are drawn
+	if strokes := replayOrder(scene, &Options{HiddenLayers: true}, replay); len(strokes) != 4 || strokes[0].Item.Line.Timestamp != v6.NewCrdtId(1, 1) {
+		t.Errorf("hidden line not replayed first")
+	}
+}
+
+func TestReplayOutput(t *testing.T) {
+	scene, _ := replayScene()
+	frame := &v6.Rect{MinX: 0, MinY: 0, MaxX: 50, MaxY: 50}
+	replay := &ReplayOptions{PointDuration: 50 * time.Millisecond, Pause: 100 * time.Millisecond, FrameRate: 20}
+
+	var out bytes.Buffer
+	if err := AnimatedSVG(&out, scene, &Options{Frame: frame}, replay); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(out.String(), "<animate"); got < 3 {
+		t.Errorf("%d animations for 3 lines", got)
+	}
+
+	out.Reset()
+	if err := GIF(&out, scene, &Options{Frame: frame}, replay); err != nil {
+		t.Fatal(err)
+	}
+	g, err := gif.DecodeAll(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) < 3 {
+		t.Errorf("%d frames", len(g.Image))
+	}
+	if bounds := g.Image[0].Bounds(); bounds.Dx() != 50 || bounds.Dy() != 50 {
+		t.Errorf("first frame %v, want the whole page", bounds)
+	}
+}
@@ -0,0 +1,218 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+type svgCanvas struct {
+	w *bufio.Writer
+	// start of the lines in seconds, the lines are animated when set
+	starts        map[*v6.LineItem]float64
+	pointDuration float64
+	// fonts that are embedded in the document
+	fonts map[*textFont]bool
+}
+
+// SVG writes the scene as an svg document, one group per layer
+func SVG(w io.Writer, scene *v6.Scene, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	scene, opts = opts.selected(scene)
+	c := &svgCanvas{
+		w: bufio.NewWriter(w),
+	}
+	return c.write(scene, opts)
+}
+
+func (c *svgCanvas) write(scene *v6.Scene, opts *Options) error {
+	frame := opts.frame(scene)
+	c.begin(frame, opts.palette())
+	drawScene(c, scene, frame, opts)
+	c.end()
+	return c.w.Flush()
+}
+
+func (c *svgCanvas) begin(frame v6.Rect, palette *Palette) {
+	x, y := svgNumber(float64(frame.MinX)), svgNumber(float64(frame.MinY))
+	width, height := svgNumber(float64(frame.Width())), svgNumber(float64(frame.Height()))
+	fmt.Fprintf(c.w, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" width="%s" height="%s" viewBox="%s %s %s %s">`+"\n",
+		width, height, x, y, width, height)
+	if bg := palette.Background; !isWhite(bg) {
+		fmt.Fprintf(c.w, `<rect x="%s" y="%s" width="%s" height="%s" fill="#%02x%02x%02x"/>`+"\n",
+			x, y, width, height, bg.R, bg.G, bg.B)
+	}
+}
+
+func (c *svgCanvas) end() {
+	c.w.WriteString("</svg>\n")
+}
+
+func (c *svgCanvas) BeginLayer(index int, layer *v6.Layer) {
+	display := ""
+	if !layer.IsVisible {
+		display = ` display="none"`
+	}
+	fmt.Fprintf(c.w, `<g id="layer%d" inkscape:groupmode="layer" inkscape:label="%s"%s>`+"\n",
+		index+1, escape(layer.Name), display)
+}
+
+func (c *svgCanvas) EndLayer() {
+	c.w.WriteString("</g>\n")
+}
+
+// Stroke writes a path for every run of points with the same width,
+// calligraphy is filled as an outline for every run with the same opacity
+func (c *svgCanvas) Stroke(s *stroke) {
+	if _, animated := c.starts[s.Item]; s.isCalligraphy() && !animated {
+		for _, run := range s.runs(func(a, b strokePoint) bool {
+			return a.Opacity == b.Opacity
+		}) {
+			opacity := ""
+			if last := run[len(run)-1]; last.Opacity != 1 {
+				opacity = fmt.Sprintf(` fill-opacity="%s"`, svgNumber(last.Opacity))
+			}
+			fmt.Fprintf(c.w, `<path d="%s" fill="#%02x%02x%02x"%s/>`+"\n",
+				calligraphyPath(run), s.Color.R, s.Color.G, s.Color.B, opacity)
+		}
+		return
+	}
+	offset := 0
+	for _, run := range s.runs(func(a, b strokePoint) bool {
+		return svgNumber(a.Width) == svgNumber(b.Width) && a.Opacity == b.Opacity
+	}) {
+		c.path(s, run, offset)
+		offset += len(run) - 1
+	}
+}
+
+// path draws the points with the width and opacity of the last point,
+// offset is the index of the first point in the line
+func (c *svgCanvas) path(s *stroke, points []strokePoint, offset int) {
+	var d strings.Builder
+	for i, p := range points {
+		cmd := "L"
+		if i == 0 {
+			cmd = "M"
+		}
+		fmt.Fprintf(&d, "%s%s %s", cmd, svgNumber(p.X), svgNumber(p.Y))
+	}
+	last := points[len(points)-1]
+	opacity := ""
+	if last.Opacity != 1 {
+		opacity = fmt.Sprintf(` stroke-opacity="%s"`, svgNumber(last.Opacity))
+	}
+	start, animated := c.starts[s.Item]
+	if s.Item == nil || !animated {
+		fmt.Fprintf(c.w, `<path d="%s" fill="none" stroke="#%02x%02x%02x"%s stroke-width="%s" stroke-linecap="round" stroke-linejoin="round"/>`+"\n",
+			d.String(), s.Color.R, s.Color.G, s.Color.B, opacity, svgNumber(last.Width))
+		return
+	}
+
+	// the dash grows over the path, the path is hidden until it starts so
+	// the caps don't show
+	length := 1.0
+	for i := 1; i < len(points); i++ {
+		length += math.Hypot(points[i].X-points[i-1].X, points[i].Y-points[i-1].Y)
+	}
+	begin := svgNumber(start + float64(offset)*c.pointDuration)
+	duration := svgNumber(math.Max(float64(len(points)-1), 1) * c.pointDuration)
+	fmt.Fprintf(c.w, `<path d="%s" fill="none" stroke="#%02x%02x%02x"%s stroke-width="%s" stroke-linecap="round" stroke-linejoin="round" visibility="hidden" stroke-dasharray="%s %s" stroke-dashoffset="%s">`+"\n",
+		d.String(), s.Color.R, s.Color.G, s.Color.B, opacity, svgNumber(last.Width),
+		svgNumber(length), svgNumber(length), svgNumber(length))
+	fmt.Fprintf(c.w, `<set attributeName="visibility" to="visible" begin="%ss" fill="freeze"/>`+"\n", begin)
+	fmt.Fprintf(c.w, `<animate attributeName="stroke-dashoffset" from="%s" to="0" begin="%ss" dur="%ss" fill="freeze"/>`+"\n",
+		svgNumber(length), begin, duration)
+	c.w.WriteString("</path>\n")
+}
+
+// Highlight writes the rectangles in a group, the highlighted text is the
+// title of the group
+func (c *svgCanvas) Highlight(h *highlight) {
+	fmt.Fprintf(c.w, `<g fill="#%02x%02x%02x" fill-opacity="%s">`+"\n",
+		h.Color.R, h.Color.G, h.Color.B, svgNumber(float64(h.Color.A)/0xff))
+	if h.Item.Text != "" {
+		fmt.Fprintf(c.w, "<title>%s</title>\n", escape(h.Item.Text))
+	}
+	for _, r := range h.Rects {
+		fmt.Fprintf(c.w, `<rect x="%s" y="%s" width="%s" height="%s"/>`+"\n",
+			svgNumber(float64(r.MinX)), svgNumber(float64(r.MinY)), svgNumber(float64(r.Width())), svgNumber(float64(r.Height())))
+	}
+	c.w.WriteString("</g>\n")
+}
+
+// Text writes the lines with the fonts embedded, so the text looks the
+// same in every viewer. Every font is embedded once in the document.
+func (c *svgCanvas) Text(lines []textLine) {
+	if c.fonts == nil {
+		c.fonts = make(map[*textFont]bool)
+	}
+	var fonts []*textFont
+	for _, line := range lines {
+		if !c.fonts[line.Font] {
+			c.fonts[line.Font] = true
+			fonts = append(fonts, line.Font)
+		}
+	}
+	if len(fonts) > 0 {
+		c.w.WriteString("<defs><style>\n")
+		for _, font := range fonts {
+			fmt.Fprintf(c.w, "@font-face{font-family:\"%s\";src:url(data:font/ttf;base64,%s)}\n",
+				font.Name, base64.StdEncoding.EncodeToString(font.Data))
+		}
+		c.w.WriteString("</style></defs>\n")
+	}
+	for _, line := range lines {
+		fmt.Fprintf(c.w, `<text x="%s" y="%s" font-family="%s" font-size="%s" fill="#%02x%02x%02x" xml:space="preserve">%s</text>`+"\n",
+			svgNumber(line.X), svgNumber(line.Y), line.Font.Name, svgNumber(line.Size),
+			line.Color.R, line.Color.G, line.Color.B, escape(line.Text))
+	}
+}
+
+// TemplateImage embeds the template file, the svg file when there is one
+func (c *svgCanvas) TemplateImage(t *Template, r v6.Rect) {
+	var uri string
+	if t.SVG != nil {
+		uri = "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(t.SVG)
+	} else {
+		var data bytes.Buffer
+		if err := png.Encode(&data, t.Image); err != nil {
+			return
+		}
+		uri = "data:image/png;base64," + base64.StdEncoding.EncodeToString(data.Bytes())
+	}
+	fmt.Fprintf(c.w, `<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="none" href="%s"/>`+"\n",
+		svgNumber(float64(r.MinX)), svgNumber(float64(r.MinY)), svgNumber(float64(r.Width())), svgNumber(float64(r.Height())), uri)
+}
+
+// svgNumber formats with 2 decimals and without trailing zeros
+func svgNumber(f float64) string {
+	if math.Abs(f) < 0.005 {
+		return "0"
+	}
+	str := strconv.FormatFloat(f, 'f', 2, 64)
+	str = strings.TrimRight(str, "0")
+	return strings.TrimSuffix(str, ".")
+}
+
+var escaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
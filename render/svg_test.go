@@ -0,0 +1,84 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// svgElements parses the document and counts the elements by name
+func svgElements(t *testing.T, data []byte) map[string]int {
+	t.Helper()
+	elements := make(map[string]int)
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return elements
+		}
+		if err != nil {
+			t.Fatalf("invalid svg: %v", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			elements[start.Name.Local]++
+		}
+	}
+}
+
+func TestSVGNotebooks(t *testing.T) {
+	for _, name := range notebooks {
+		scene := readNotebook(t, name)
+		var out bytes.Buffer
+		if err := SVG(&out, scene, nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		elements := svgElements(t, out.Bytes())
+		if elements["svg"] != 1 {
+			t.Errorf("%s: %d svg elements", name, elements["svg"])
+		}
+		if got := strings.Count(out.String(), `inkscape:groupmode="layer"`); got != len(scene.Layers) {
+			t.Errorf("%s: %d layers, want %d", name, got, len(scene.Layers))
+		}
+		if elements["path"] == 0 {
+			t.Errorf("%s: no lines drawn", name)
+		}
+	}
+}
+
+func TestSVGLayers(t *testing.T) {
+	scene := testScene(testLine(v6.ToolFineliner, 10, 10, 100, 10, 100, 100))
+	scene.Layers = append(scene.Layers, &v6.Layer{Name: "<hidden>", Lines: []*v6.LineItem{
+		testLine(v6.ToolFineliner, 20, 20, 50, 50),
+	}})
+	frame := &v6.Rect{MinX: 0, MinY: 0, MaxX: 200, MaxY: 200}
+	var out bytes.Buffer
+	if err := SVG(&out, scene, &Options{Frame: frame, HiddenLayers: true}); err != nil {
+		t.Fatal(err)
+	}
+	svg := out.String()
+	svgElements(t, out.Bytes())
+	for _, want := range []string{
+		`viewBox="0 0 200 200"`,
+		`id="layer1" inkscape:groupmode="layer" inkscape:label="Layer 1">`,
+		`id="layer2" inkscape:groupmode="layer" inkscape:label="&lt;hidden&gt;" display="none">`,
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("%s missing in\n%s", want, svg)
+		}
+	}
+	if got := strings.Count(svg, "<path"); got != 2 {
+		t.Errorf("%d paths, want 2", got)
+	}
+
+	out.Reset()
+	if err := SVG(&out, scene, &Options{Frame: frame}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), `id="layer2"`) {
+		t.Error("hidden layer drawn")
+	}
+}
@@ -0,0 +1,245 @@
+package render
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// Template is the background of a page, it is drawn beneath all layers.
+// It is either a built in layout or the image of a template file.
+type Template struct {
+	Name string
+	// Image of the template file, drawn over the page
+	Image image.Image
+	// SVG is the svg template file, only the svg output can draw it. Other
+	// outputs need Image.
+	SVG []byte
+
+	layout layout
+}
+
+// templateLine is a line of a built in template, a dot when both ends are
+// the same
+type templateLine struct {
+	X0, Y0, X1, Y1 float64
+	Width          float64
+}
+
+// layout draws a built in template over the frame
+type layout func(frame v6.Rect) []templateLine
+
+// the lines of the templates are drawn with the gray pen, light enough to
+// stay behind the writing
+const templateOpacity = 0.5
+
+const (
+	templateLineWidth = 2
+	templateDotWidth  = 6
+	// the lined templates leave room for a title
+	templateHeader = 180
+)
+
+// templateLayouts the built in templates, named like the templates of the
+// device
+var templateLayouts = map[string]layout{
+	"blank":          func(v6.Rect) []templateLine { return nil },
+	"p lines small":  lined(52, 0),
+	"p lines medium": lined(68, 0),
+	"p lines large":  lined(84, 0),
+	"p margin small": lined(52, 140),
+	"p margin large": lined(84, 140),
+	"p grid small":   grid(44),
+	"p grid medium":  grid(66),
+	"p grid large":   grid(88),
+	"p dots s":       dots(44),
+	"p dots large":   dots(66),
+	"p cornell":      cornell,
+
+	"lined":   lined(68, 0),
+	"grid":    grid(66),
+	"dots":    dots(44),
+	"cornell": cornell,
+}
+
+// TemplateNames the names of the built in templates
+func TemplateNames() (names []string) {
+	for name := range templateLayouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// lined draws ruled lines below the header and a margin line when margin is
+// not 0
+func lined(spacing, margin float64) layout {
+	return func(frame v6.Rect) (lines []templateLine) {
+		lines = ruled(frame, float64(frame.MinX), templateHeader, spacing)
+		if margin > 0 {
+			x := float64(Page.MinX) + margin
+			lines = append(lines, templateLine{X0: x, Y0: float64(frame.MinY), X1: x, Y1: float64(frame.MaxY), Width: templateLineWidth})
+		}
+		return
+	}
+}
+
+// ruled draws horizontal lines from x to the right of the frame, starting
+// at top
+func ruled(frame v6.Rect, x, top, spacing float64) (lines []templateLine) {
+	for y := top; y <= float64(frame.MaxY); y += spacing {
+		if y >= float64(frame.MinY) {
+			lines = append(lines, templateLine{X0: x, Y0: y, X1: float64(frame.MaxX), Y1: y, Width: templateLineWidth})
+		}
+	}
+	return
+}
+
+// grid draws squares that are centered on the page
+func grid(spacing float64) layout {
+	return func(frame v6.Rect) (lines []templateLine) {
+		for _, x := range steps(float64(frame.MinX), float64(frame.MaxX), spacing) {
+			lines = append(lines, templateLine{X0: x, Y0: float64(frame.MinY), X1: x, Y1: float64(frame.MaxY), Width: templateLineWidth})
+		}
+		for _, y := range steps(float64(frame.MinY), float64(frame.MaxY), spacing) {
+			lines = append(lines, templateLine{X0: float64(frame.MinX), Y0: y, X1: float64(frame.MaxX), Y1: y, Width: templateLineWidth})
+		}
+		return
+	}
+}
+
+// dots draws a dot where the lines of the grid would cross
+func dots(spacing float64) layout {
+	return func(frame v6.Rect) (lines []templateLine) {
+		for _, y := range steps(float64(frame.MinY), float64(frame.MaxY), spacing) {
+			for _, x := range steps(float64(frame.MinX), float64(frame.MaxX), spacing) {
+				lines = append(lines, templateLine{X0: x, Y0: y, X1: x, Y1: y, Width: templateDotWidth})
+			}
+		}
+		return
+	}
+}
+
+// cornell has a cue column on the left and a summary at the bottom of every
+// page
+func cornell(frame v6.Rect) (lines []templateLine) {
+	const (
+		spacing = 68
+		cues    = 400
+		summary = 380
+	)
+	left, right := float64(frame.MinX), float64(frame.MaxX)
+	x := float64(Page.MinX) + cues
+	for top := pageTop(float64(frame.MinY)); top < float64(frame.MaxY); top += PageHeight {
+		bottom := top + PageHeight - summary
+		page := v6.Rect{MinX: frame.MinX, MinY: float32(top), MaxX: frame.MaxX, MaxY: float32(bottom)}
+		lines = append(lines, ruled(page, left, top+templateHeader, spacing)...)
+		lines = append(lines,
+			templateLine{X0: x, Y0: top + templateHeader, X1: x, Y1: bottom, Width: templateLineWidth},
+			templateLine{X0: left, Y0: bottom, X1: right, Y1: bottom, Width: templateLineWidth})
+	}
+	return
+}
+
+// steps returns the multiples of spacing between min and max, so every
+// page has the same pattern
+func steps(min, max, spacing float64) (values []float64) {
+	for v := math.Ceil(min/spacing) * spacing; v <= max; v += spacing {
+		values = append(values, v)
+	}
+	return
+}
+
+// pageTop is the top of the page that contains y, pages are repeated
+// below each other
+func pageTop(y float64) float64 {
+	return math.Floor(y/PageHeight) * PageHeight
+}
+
+// drawTemplate draws the template in its own layer, images are repeated for
+// every page of the frame
+func drawTemplate(c canvas, t *Template, frame v6.Rect, palette *Palette) {
+	c.BeginLayer(-1, &v6.Layer{Name: "Template", IsVisible: true})
+	if t.layout != nil {
+		col := palette.Color(v6.ColorGray)
+		for _, line := range t.layout(frame) {
+			c.Stroke(&stroke{
+				Color: col,
+				Points: []strokePoint{
+					{X: line.X0, Y: line.Y0, Width: line.Width, Opacity: templateOpacity},
+					{X: line.X1, Y: line.Y1, Width: line.Width, Opacity: templateOpacity},
+				},
+			})
+		}
+	}
+	if t.Image != nil || t.SVG != nil {
+		for top := pageTop(float64(frame.MinY)); top < float64(frame.MaxY); top += PageHeight {
+			c.TemplateImage(t, v6.Rect{MinX: Page.MinX, MinY: float32(top), MaxX: Page.MaxX, MaxY: float32(top + PageHeight)})
+		}
+	}
+	c.EndLayer()
+}
+
+// LoadTemplate returns the template with the name. A template file in dir
+// is used first, name.svg and name.png as on the device, then the built in
+// templates.
+func LoadTemplate(name, dir string) (*Template, error) {
+	if dir != "" {
+		t, err := readTemplateFiles(name, dir)
+		if err == nil {
+			return t, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	if layout, ok := templateLayouts[strings.ToLower(name)]; ok {
+		return &Template{Name: name, layout: layout}, nil
+	}
+	return nil, fmt.Errorf("unknown template: %s", name)
+}
+
+// readTemplateFiles reads the svg and the png of the template, one of them
+// has to exist
+func readTemplateFiles(name, dir string) (t *Template, err error) {
+	t = &Template{Name: name}
+	base := filepath.Join(dir, name)
+	t.SVG, err = os.ReadFile(base + ".svg")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	file, err := os.Open(base + ".png")
+	if errors.Is(err, fs.ErrNotExist) && t.SVG != nil {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	t.Image, _, err = image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// ReadPageData reads the .pagedata file of a document, the template of
+// every page on its own line
+func ReadPageData(r io.Reader) (templates []string, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		templates = append(templates, strings.TrimSpace(scanner.Text()))
+	}
+	return templates, scanner.Err()
+}
@@ -0,0 +1,132 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+func TestLoadTemplate(t *testing.T) {
+	for _, name := range TemplateNames() {
+		if _, err := LoadTemplate(name, ""); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := LoadTemplate("no such template", ""); err == nil {
+		t.Error("no error for an unknown template")
+	}
+
+	dir := t.TempDir()
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, image.NewGray(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"image.png":  buffer.Bytes(),
+		"both.png":   buffer.Bytes(),
+		"both.svg":   []byte("<svg/>"),
+		"vector.svg": []byte("<svg/>"),
+		"broken.png": []byte("not a png"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name         string
+		image, svg   bool
+		builtin, err bool
+	}{
+		{"image", true, false, false, false},
+		{"both", true, true, false, false},
+		{"vector", false, true, false, false},
+		{"P Grid small", false, false, true, false},
+		{"broken", false, false, false, true},
+		{"missing", false, false, false, true},
+	}
+	for _, test := range tests {
+		template, err := LoadTemplate(test.name, dir)
+		if (err != nil) != test.err {
+			t.Errorf("%s: error %v", test.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if (template.Image != nil) != test.image || (template.SVG != nil) != test.svg || (template.layout != nil) != test.builtin {
+			t.Errorf("%s: image %t, svg %t, built in %t", test.name, template.Image != nil, template.SVG != nil, template.layout != nil)
+		}
+	}
+}
+
+func TestTemplateLayouts(t *testing.T) {
+	frame := v6.Rect{MinX: 0, MinY: 0, MaxX: PageWidth, MaxY: 2 * PageHeight}
+	tests := []struct {
+		name  string
+		lines int
+	}{
+		{"blank", 0},
+		// from the header to the end of the second page
+		{"p lines large", (2*PageHeight-templateHeader)/84 + 1},
+		{"p margin large", (2*PageHeight-templateHeader)/84 + 2},
+		{"p grid large", len(steps(0, PageWidth, 88)) + len(steps(0, 2*PageHeight, 88))},
+		{"p dots large", len(steps(0, PageWidth, 66)) * len(steps(0, 2*PageHeight, 66))},
+	}
+	for _, test := range tests {
+		if lines := templateLayouts[test.name](frame); len(lines) != test.lines {
+			t.Errorf("%s: %d lines, want %d", test.name, len(lines), test.lines)
+		}
+	}
+
+	// a cue column and a summary line on every page
+	columns := 0
+	for _, line := range cornell(frame) {
+		if line.X0 == line.X1 {
+			columns++
+		}
+	}
+	if columns != 2 {
+		t.Errorf("%d cue columns on two pages", columns)
+	}
+}
+
+func TestDrawTemplate(t *testing.T) {
+	template, err := LoadTemplate("p lines small", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := &v6.Rect{MinX: 0, MinY: 0, MaxX: 200, MaxY: 400}
+	img := Raster(testScene(), &Options{Frame: frame, DPI: DPI, Template: template})
+	line := func(y int) color.Color { return img.At(100, y) }
+	if c := line(templateHeader); isInk(c) || c == (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("template line drawn %v, want light gray", c)
+	}
+	if c := line(templateHeader - 20); c != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("header drawn %v", c)
+	}
+
+	var out bytes.Buffer
+	if err := SVG(&out, testScene(), &Options{Frame: frame, Template: template}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `inkscape:label="Template"`) {
+		t.Error("no template layer in the svg")
+	}
+}
+
+func TestReadPageData(t *testing.T) {
+	templates, err := ReadPageData(strings.NewReader("Blank\n P Grid small \r\nP Lines medium"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(templates, "|"); got != "Blank|P Grid small|P Lines medium" {
+		t.Errorf("templates %q", got)
+	}
+}
@@ -0,0 +1,187 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"io"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// TerminalMode is how a page is drawn in a terminal
+type TerminalMode int
+
+const (
+	// Braille draws 2x4 pixels with every character, works everywhere
+	Braille TerminalMode = iota
+	// HalfBlocks draws 1x2 pixels with every character in 24 bit colors
+	HalfBlocks
+	// Sixel draws pixels, not every terminal supports it
+	Sixel
+)
+
+// pixels of a character cell, the size of sixel output is a guess as
+// terminals don't tell the size of their font
+const sixelCellWidth = 10
+
+// brailleDots the bit of every dot of a braille character, by row and
+// column
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Terminal draws the page into a terminal, the page is as wide as the
+// columns
+func Terminal(w io.Writer, scene *v6.Scene, opts *Options, mode TerminalMode, columns int) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	scene, opts = opts.selected(scene)
+	width := columns
+	switch mode {
+	case Braille:
+		width = columns * 2
+	case Sixel:
+		width = columns * sixelCellWidth
+	}
+	previewOpts := *opts
+	frame := opts.frame(scene)
+	previewOpts.Frame = &frame
+	previewOpts.Display = nil
+	previewOpts.DPI = DPI * float64(width) / float64(frame.Width())
+	img := Raster(scene, &previewOpts)
+
+	out := bufio.NewWriter(w)
+	switch mode {
+	case Braille:
+		writeBraille(out, img, previewOpts.palette().Background)
+	case HalfBlocks:
+		writeHalfBlocks(out, img)
+	case Sixel:
+		writeSixel(out, img)
+	default:
+		return fmt.Errorf("unknown terminal mode: %d", mode)
+	}
+	return out.Flush()
+}
+
+// writeBraille dithers the page to two levels, the ink is a dot. The ink
+// is light on a dark background.
+func writeBraille(w *bufio.Writer, img image.Image, background color.NRGBA) {
+	bw := (&Display{Levels: 2, White: 0xff}).show(toRGBA(img)).(*image.Gray)
+	dark := color.GrayModel.Convert(background).(color.Gray).Y < 0x80
+	bounds := bw.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 4 {
+		for x := bounds.Min.X; x < bounds.Max.X; x += 2 {
+			r := rune(0x2800)
+			for dy, row := range brailleDots {
+				for dx, bit := range row {
+					p := image.Pt(x+dx, y+dy)
+					if p.In(bounds) && (bw.GrayAt(p.X, p.Y).Y < 0x80) != dark {
+						r |= bit
+					}
+				}
+			}
+			w.WriteRune(r)
+		}
+		w.WriteString("\n")
+	}
+}
+
+// writeHalfBlocks draws two pixels with the upper half block, the upper in
+// the foreground color and the lower in the background color
+func writeHalfBlocks(w *bufio.Writer, img image.Image) {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 2 {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			top := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			bottom := top
+			if y+1 < bounds.Max.Y {
+				bottom = color.RGBAModel.Convert(img.At(x, y+1)).(color.RGBA)
+			}
+			fmt.Fprintf(w, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+		}
+		w.WriteString("\x1b[0m\n")
+	}
+}
+
+// writeSixel writes the image as sixel graphics with 256 colors. Every
+// band of 6 rows is written once for every color in it.
+func writeSixel(w *bufio.Writer, img image.Image) {
+	bounds := img.Bounds()
+	paletted := image.NewPaletted(bounds, palette.Plan9)
+	draw.FloydSteinberg.Draw(paletted, bounds, img, bounds.Min)
+
+	fmt.Fprintf(w, "\x1bPq\"1;1;%d;%d", bounds.Dx(), bounds.Dy())
+	defined := make(map[uint8]bool)
+	for _, index := range paletted.Pix {
+		if defined[index] {
+			continue
+		}
+		defined[index] = true
+		r, g, b, _ := paletted.Palette[index].RGBA()
+		fmt.Fprintf(w, "#%d;2;%d;%d;%d", index, r*100/0xffff, g*100/0xffff, b*100/0xffff)
+	}
+	sixels := make([]byte, bounds.Dx())
+	for top := bounds.Min.Y; top < bounds.Max.Y; top += 6 {
+		used := make(map[uint8]bool)
+		for y := top; y < top+6 && y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				used[paletted.ColorIndexAt(x, y)] = true
+			}
+		}
+		for index := 0; index < len(paletted.Palette); index++ {
+			if !used[uint8(index)] {
+				continue
+			}
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				bits := byte(0)
+				for dy := 0; dy < 6 && top+dy < bounds.Max.Y; dy++ {
+					if paletted.ColorIndexAt(x, top+dy) == uint8(index) {
+						bits |= 1 << dy
+					}
+				}
+				sixels[x-bounds.Min.X] = '?' + bits
+			}
+			fmt.Fprintf(w, "#%d", index)
+			writeSixelRuns(w, sixels)
+			w.WriteByte('$')
+		}
+		w.WriteByte('-')
+	}
+	w.WriteString("\x1b\\")
+}
+
+// writeSixelRuns writes repeated sixels as !count
+func writeSixelRuns(w *bufio.Writer, sixels []byte) {
+	for i := 0; i < len(sixels); {
+		j := i
+		for j < len(sixels) && sixels[j] == sixels[i] {
+			j++
+		}
+		if j-i > 3 {
+			fmt.Fprintf(w, "!%d%c", j-i, sixels[i])
+		} else {
+			for k := i; k < j; k++ {
+				w.WriteByte(sixels[k])
+			}
+		}
+		i = j
+	}
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
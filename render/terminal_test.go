@@ -0,0 +1,128 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// testImage is black where the rows have a '#', white elsewhere
+func testImage(rows ...string) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			img.Set(x, y, color.White)
+			if c == '#' {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	return img
+}
+
+func written(write func(w *bufio.Writer)) string {
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	write(w)
+	w.Flush()
+	return out.String()
+}
+
+func TestWriteSixelRuns(t *testing.T) {
+	tests := []struct {
+		sixels, want string
+	}{
+		{"", ""},
+		{"abc", "abc"},
+		{"aaab", "aaab"},
+		{"aaaab", "!4ab"},
+		{"a??????b@@@@", "a!6?b!4@"},
+	}
+	for _, test := range tests {
+		if got := written(func(w *bufio.Writer) { writeSixelRuns(w, []byte(test.sixels)) }); got != test.want {
+			t.Errorf("%q: %q, want %q", test.sixels, got, test.want)
+		}
+	}
+}
+
+func TestWriteSixel(t *testing.T) {
+	img := testImage(
+		"#.#",
+		"##.",
+	)
+	// black is color 0 and white 255 of the palette, the bits of a sixel
+	// are the rows from the top
+	want := "\x1bPq\"1;1;3;2" +
+		"#0;2;0;0;0#255;2;100;100;100" +
+		"#0BA@$#255?@A$-" +
+		"\x1b\\"
+	if got := written(func(w *bufio.Writer) { writeSixel(w, img) }); got != want {
+		t.Errorf("%q, want %q", got, want)
+	}
+}
+
+func TestWriteBraille(t *testing.T) {
+	img := testImage(
+		"#...",
+		"#...",
+		"#...",
+		"#..#",
+	)
+	tests := []struct {
+		name       string
+		background color.NRGBA
+		want       string
+	}{
+		{"light", DefaultPalette.Background, "⡇⢀\n"},
+		// the ink is the light part
+		{"dark", DarkPalette.Background, "⢸⡿\n"},
+	}
+	for _, test := range tests {
+		if got := written(func(w *bufio.Writer) { writeBraille(w, img, test.background) }); got != test.want {
+			t.Errorf("%s: %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWriteHalfBlocks(t *testing.T) {
+	img := testImage(
+		"#.",
+		".#",
+		"#.",
+	)
+	black, white := "0;0;0", "255;255;255"
+	block := func(top, bottom string) string {
+		return "\x1b[38;2;" + top + "m\x1b[48;2;" + bottom + "m▀"
+	}
+	want := block(black, white) + block(white, black) + "\x1b[0m\n" +
+		block(black, black) + block(white, white) + "\x1b[0m\n"
+	if got := written(func(w *bufio.Writer) { writeHalfBlocks(w, img) }); got != want {
+		t.Errorf("%q, want %q", got, want)
+	}
+}
+
+func TestTerminalColumns(t *testing.T) {
+	scene := testScene(testLine(v6.ToolFineliner, -500, 500, 500, 500))
+	var out bytes.Buffer
+	if err := Terminal(&out, scene, nil, Braille, 40); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	for i, line := range lines {
+		if n := utf8.RuneCountInString(line); n != 40 {
+			t.Fatalf("line %d: %d columns, want 40", i, n)
+		}
+	}
+	if strings.Trim(out.String(), "\u2800\n") == "" {
+		t.Error("the line is not drawn")
+	}
+	if err := Terminal(&out, scene, nil, TerminalMode(99), 40); err == nil {
+		t.Error("no error for an unknown mode")
+	}
+}
@@ -0,0 +1,344 @@
+package render
+
+import (
+	"image/color"
+	"strings"
+	"unicode"
+
+	v6 "github.com/ddvk/reader/v6"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// textFont is an embedded font, text is measured with it and it is
+// embedded in the output so the layout is the same everywhere
+type textFont struct {
+	Name string
+	Data []byte
+	font *sfnt.Font
+}
+
+var (
+	regularFont = parseFont("GoRegular", goregular.TTF)
+	boldFont    = parseFont("GoBold", gobold.TTF)
+)
+
+func parseFont(name string, data []byte) *textFont {
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		panic(err)
+	}
+	return &textFont{
+		Name: name,
+		Data: data,
+		font: f,
+	}
+}
+
+// unitsPerEm the size of the font units
+func (f *textFont) unitsPerEm() float64 {
+	return float64(f.font.UnitsPerEm())
+}
+
+// ppem measures in font units
+func (f *textFont) ppem() fixed.Int26_6 {
+	return fixed.I(int(f.font.UnitsPerEm()))
+}
+
+// glyph returns the glyph of the rune, 0 when the font does not have it
+func (f *textFont) glyph(r rune) sfnt.GlyphIndex {
+	var b sfnt.Buffer
+	g, err := f.font.GlyphIndex(&b, r)
+	if err != nil {
+		return 0
+	}
+	return g
+}
+
+// advance of the glyph in font units
+func (f *textFont) advance(g sfnt.GlyphIndex) float64 {
+	var b sfnt.Buffer
+	advance, err := f.font.GlyphAdvance(&b, g, f.ppem(), font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return float64(advance) / 64
+}
+
+// width of the text at the font size
+func (f *textFont) width(text string, size float64) float64 {
+	width := 0.0
+	for _, r := range text {
+		width += f.advance(f.glyph(r))
+	}
+	return width * size / f.unitsPerEm()
+}
+
+// metrics of the font in font units
+func (f *textFont) metrics() font.Metrics {
+	var b sfnt.Buffer
+	m, _ := f.font.Metrics(&b, f.ppem(), font.HintingNone)
+	return m
+}
+
+// outline adds the outline of the glyph to the rasterizer, x and y are
+// the origin of the glyph in pixels
+func (f *textFont) outline(r *vector.Rasterizer, g sfnt.GlyphIndex, size, x, y float64) {
+	var b sfnt.Buffer
+	segments, err := f.font.LoadGlyph(&b, g, fixed.Int26_6(size*64), nil)
+	if err != nil {
+		return
+	}
+	point := func(p fixed.Point26_6) (float32, float32) {
+		return float32(x + float64(p.X)/64), float32(y + float64(p.Y)/64)
+	}
+	open := false
+	for _, s := range segments {
+		x0, y0 := point(s.Args[0])
+		switch s.Op {
+		case sfnt.SegmentOpMoveTo:
+			if open {
+				r.ClosePath()
+			}
+			r.MoveTo(x0, y0)
+			open = true
+		case sfnt.SegmentOpLineTo:
+			r.LineTo(x0, y0)
+		case sfnt.SegmentOpQuadTo:
+			x1, y1 := point(s.Args[1])
+			r.QuadTo(x0, y0, x1, y1)
+		case sfnt.SegmentOpCubeTo:
+			x1, y1 := point(s.Args[1])
+			x2, y2 := point(s.Args[2])
+			r.CubeTo(x0, y0, x1, y1, x2, y2)
+		}
+	}
+	if open {
+		r.ClosePath()
+	}
+}
+
+// paragraphStyle is how a paragraph style is laid out
+type paragraphStyle struct {
+	Font       *textFont
+	Size       float64
+	LineHeight float64
+	// Indent of the text from the left of the block
+	Indent float64
+	// Marker is drawn before the first line, at MarkerIndent
+	Marker       string
+	MarkerIndent float64
+}
+
+var plainStyle = paragraphStyle{
+	Font:       regularFont,
+	Size:       32,
+	LineHeight: 71,
+}
+
+// the sizes and line heights of the device
+var paragraphStyles = map[v6.ParagraphStyle]paragraphStyle{
+	v6.StyleBasic: plainStyle,
+	v6.StylePlain: plainStyle,
+	v6.StyleHeading: {
+		Font:       regularFont,
+		Size:       50,
+		LineHeight: 150,
+	},
+	v6.StyleBold: {
+		Font:       boldFont,
+		Size:       32,
+		LineHeight: 70,
+	},
+	v6.StyleBullet: {
+		Font:         regularFont,
+		Size:         32,
+		LineHeight:   71,
+		Indent:       60,
+		Marker:       "•",
+		MarkerIndent: 24,
+	},
+	v6.StyleBullet2: {
+		Font:         regularFont,
+		Size:         32,
+		LineHeight:   71,
+		Indent:       120,
+		Marker:       "◦",
+		MarkerIndent: 84,
+	},
+	v6.StyleCheckbox: {
+		Font:         regularFont,
+		Size:         32,
+		LineHeight:   71,
+		Indent:       60,
+		Marker:       "□",
+		MarkerIndent: 16,
+	},
+	v6.StyleCheckboxChecked: {
+		Font:         regularFont,
+		Size:         32,
+		LineHeight:   71,
+		Indent:       60,
+		Marker:       "■",
+		MarkerIndent: 16,
+	},
+}
+
+// textLine is a line of typed text, positioned at its baseline
+type textLine struct {
+	X, Y  float64
+	Font  *textFont
+	Size  float64
+	Color color.NRGBA
+	Text  string
+	// Ids of the characters of Text, empty for list markers
+	Ids []v6.CrdtId
+}
+
+// paragraph is the text between two new lines, Id is the id of the new line
+// before it, 0 for the first paragraph
+type paragraph struct {
+	Id   v6.CrdtId
+	Text []rune
+	Ids  []v6.CrdtId
+}
+
+func paragraphs(text *v6.SceneTextItem) []paragraph {
+	current := paragraph{}
+	var result []paragraph
+	for _, c := range text.Chars() {
+		if c.Deleted {
+			continue
+		}
+		if c.Rune == '\n' {
+			result = append(result, current)
+			current = paragraph{Id: c.Id}
+			continue
+		}
+		current.Text = append(current.Text, c.Rune)
+		current.Ids = append(current.Ids, c.Id)
+	}
+	return append(result, current)
+}
+
+// layoutText wraps the paragraphs at the width of the text block, every
+// paragraph takes at least one line
+func layoutText(text *v6.SceneTextItem, palette *Palette) []textLine {
+	lines, _ := layoutParagraphs(text, palette)
+	return lines
+}
+
+// layoutParagraphs lays out the text like layoutText, starts is where the
+// new line of every paragraph is
+func layoutParagraphs(text *v6.SceneTextItem, palette *Palette) (lines []textLine, starts map[v6.CrdtId][2]float64) {
+	starts = make(map[v6.CrdtId][2]float64)
+	styles := make(map[v6.CrdtId]v6.ParagraphStyle)
+	for _, format := range text.Formats {
+		styles[format.CharId] = format.Style.Value
+	}
+	col := palette.Color(v6.ColorBlack)
+	top := text.Position.Y
+	for _, p := range paragraphs(text) {
+		style, ok := paragraphStyles[styles[p.Id]]
+		if !ok {
+			style = plainStyle
+		}
+		x := text.Position.X + style.Indent
+		// position in the paragraph, the spaces at line breaks are dropped
+		pos := 0
+		for i, line := range wrap(string(p.Text), style, float64(text.Width)-style.Indent) {
+			runes := []rune(line)
+			for pos < len(p.Text) && unicode.IsSpace(p.Text[pos]) && !strings.HasPrefix(string(p.Text[pos:]), line) {
+				pos++
+			}
+			end := pos + len(runes)
+			if end > len(p.Ids) {
+				end = len(p.Ids)
+			}
+			ids := p.Ids[pos:end]
+			pos = end
+			// center the capitals in the line
+			baseline := top + (style.LineHeight+style.Size*0.7)/2
+			if i == 0 && p.Id != 0 {
+				starts[p.Id] = [2]float64{x, baseline}
+			}
+			if i == 0 && style.Marker != "" {
+				lines = append(lines, textLine{
+					X:     text.Position.X + style.MarkerIndent,
+					Y:     baseline,
+					Font:  style.Font,
+					Size:  style.Size,
+					Color: col,
+					Text:  style.Marker,
+				})
+			}
+			if line != "" {
+				lines = append(lines, textLine{
+					X:     x,
+					Y:     baseline,
+					Font:  style.Font,
+					Size:  style.Size,
+					Color: col,
+					Text:  line,
+					Ids:   ids,
+				})
+			}
+			top += style.LineHeight
+		}
+	}
+	return
+}
+
+// wrap breaks the text at spaces so the lines fit the width, words that
+// are too long are broken anywhere. Empty text is one empty line, text
+// without a width is not wrapped.
+func wrap(text string, style paragraphStyle, width float64) (lines []string) {
+	if width <= 0 {
+		return []string{strings.TrimRightFunc(text, unicode.IsSpace)}
+	}
+	fits := func(s string) bool {
+		return style.Font.width(strings.TrimRightFunc(s, unicode.IsSpace), style.Size) <= width
+	}
+	line := ""
+	for _, word := range words(text) {
+		if fits(line + word) {
+			line += word
+			continue
+		}
+		if line != "" {
+			lines = append(lines, strings.TrimRightFunc(line, unicode.IsSpace))
+			line = ""
+		}
+		for _, r := range word {
+			if line != "" && !fits(line+string(r)) {
+				lines = append(lines, line)
+				line = ""
+			}
+			line += string(r)
+		}
+	}
+	return append(lines, strings.TrimRightFunc(line, unicode.IsSpace))
+}
+
+// words splits after every run of spaces, the spaces stay with the word
+func words(text string) (words []string) {
+	start := 0
+	space := false
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			space = true
+		} else if space {
+			words = append(words, text[start:i])
+			start = i
+			space = false
+		}
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	text := "the quick brown fox jumps over the lazy dog "
+	word := plainStyle.Font.width("quick", plainStyle.Size)
+	tests := []struct {
+		name  string
+		text  string
+		width float64
+		lines int
+	}{
+		{"empty", "", 100, 1},
+		{"no width", text, 0, 1},
+		{"negative width", text, -1, 1},
+		{"wide", text, 100 * word, 1},
+		{"narrow", text, word * 1.5, 8},
+		{"long word", "abcdefghij", word, 3},
+	}
+	for _, test := range tests {
+		lines := wrap(test.text, plainStyle, test.width)
+		if len(lines) != test.lines {
+			t.Errorf("%s: %d lines %q, want %d", test.name, len(lines), lines, test.lines)
+		}
+		if joined := strings.Join(strings.Fields(strings.Join(lines, " ")), ""); joined != strings.Join(strings.Fields(test.text), "") {
+			t.Errorf("%s: text changed to %q", test.name, lines)
+		}
+	}
+}
+
+func TestLayoutText(t *testing.T) {
+	scene := readNotebook(t, "v6_text.rm")
+	lines := layoutText(scene.Text, DefaultPalette)
+	var text []string
+	for i, line := range lines {
+		text = append(text, line.Text)
+		if i > 0 && line.Y <= lines[i-1].Y {
+			t.Errorf("line %q above the line before", line.Text)
+		}
+	}
+	if got := strings.Join(text, "|"); !strings.Contains(got, "abasd|тест") {
+		t.Errorf("lines %q", got)
+	}
+}
+
+// the fonts are embedded once however often text is written
+func TestSVGFonts(t *testing.T) {
+	scene := readNotebook(t, "v6_text.rm")
+	lines := layoutText(scene.Text, DefaultPalette)
+	fonts := make(map[*textFont]bool)
+	for _, line := range lines {
+		fonts[line.Font] = true
+	}
+	var out bytes.Buffer
+	c := &svgCanvas{w: bufio.NewWriter(&out)}
+	c.Text(lines)
+	c.Text(lines)
+	c.Text(nil)
+	c.w.Flush()
+	if got := strings.Count(out.String(), "@font-face"); got != len(fonts) {
+		t.Errorf("%d fonts embedded, want %d", got, len(fonts))
+	}
+	if got := strings.Count(out.String(), "<defs>"); got != 1 {
+		t.Errorf("%d font definitions, want 1", got)
+	}
+}
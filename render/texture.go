@@ -0,0 +1,170 @@
+package render
+
+import (
+	"image"
+	"image/draw"
+	"math"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// grain is value noise over the page, the same seed gives the same grain
+// at every resolution
+type grain struct {
+	seed uint64
+}
+
+func newGrain(opts *Options) *grain {
+	if !opts.Texture {
+		return nil
+	}
+	return &grain{seed: uint64(opts.Seed)}
+}
+
+// lattice is the noise at a corner of the grid, 0 to 1
+func (g *grain) lattice(seed uint64, x, y int64) float64 {
+	h := seed ^ uint64(x)*0x9e3779b97f4a7c15 ^ uint64(y)*0xc2b2ae3d27d4eb4f
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return float64(h>>11) / (1 << 53)
+}
+
+// at interpolates the noise between the corners of the grid
+func (g *grain) at(seed uint64, x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := smoothstep(0, 1, x-x0), smoothstep(0, 1, y-y0)
+	ix, iy := int64(x0), int64(y0)
+	top := lerp(g.lattice(seed, ix, iy), g.lattice(seed, ix+1, iy), fx)
+	bottom := lerp(g.lattice(seed, ix, iy+1), g.lattice(seed, ix+1, iy+1), fx)
+	return lerp(top, bottom, fy)
+}
+
+// tooth is how a tool takes the grain of the paper
+type tooth struct {
+	// size of the grain along and across the stroke in pixels of the device
+	along, across float64
+	// density is how much of the grain gets ink, 0 to 1
+	density func(pressure, speed float64) float64
+	// streaks are the bristles of a brush, every stroke has its own
+	streaks bool
+}
+
+var teeth = map[v6.Tool]tooth{
+	v6.ToolPencil: {
+		along:  2,
+		across: 1.2,
+		density: func(pressure, speed float64) float64 {
+			return clamp(0.3+0.8*pressure-0.1*speed/35, 0.15, 1)
+		},
+	},
+	v6.ToolMechanicalPencil: {
+		along:  1.5,
+		across: 1,
+		density: func(pressure, speed float64) float64 {
+			return clamp(0.45+0.5*pressure-0.05*speed/35, 0.3, 0.95)
+		},
+	},
+	v6.ToolPaintbrush: {
+		along:   14,
+		across:  1.5,
+		streaks: true,
+		density: func(pressure, speed float64) float64 {
+			return clamp(0.4+0.7*pressure-0.2*speed/50, 0.25, 1)
+		},
+	},
+}
+
+// directions the grain of a stroke is turned to, the grain looks the same
+// both ways
+const grainDirections = 8
+
+// texturedStroke fills the stroke through the grain, light and fast strokes
+// leave ink only on the peaks of the grain. Every run of points with the
+// same opacity and direction takes the grain on its own, the runs are
+// joined in one mask so they don't darken where they meet.
+func (c *rasterCanvas) texturedStroke(s *stroke, t tooth) {
+	area := c.strokeBounds(s)
+	if area.Empty() {
+		return
+	}
+	ink := image.NewAlpha(area)
+	seed := c.grain.seed
+	if t.streaks {
+		seed ^= uint64(s.Item.Id) * 0x9e3779b97f4a7c15
+	}
+	bucket := func(p strokePoint) int {
+		turn := math.Mod(p.Direction, math.Pi) / math.Pi
+		return int(turn * grainDirections)
+	}
+	// the grain is sharp at the size of the device and blurs into an
+	// even tone when it gets smaller than a pixel
+	soft := math.Min(0.12/math.Min(c.scale, 1), 0.5)
+
+	for _, run := range s.runs(func(a, b strokePoint) bool {
+		return a.Opacity == b.Opacity && bucket(a) == bucket(b)
+	}) {
+		points := make([]strokePoint, len(run))
+		bounds := image.Rectangle{}
+		var pressure, speed, dx, dy float64
+		for i, p := range run {
+			x, y := c.toImage(p.X, p.Y)
+			radius := math.Max(p.Width*c.scale, minRasterWidth) / 2
+			points[i] = strokePoint{X: x, Y: y, Width: radius}
+			bounds = bounds.Union(image.Rect(
+				int(math.Floor(x-radius)), int(math.Floor(y-radius)),
+				int(math.Ceil(x+radius))+1, int(math.Ceil(y+radius))+1))
+			pressure += p.Pressure
+			speed += p.Speed
+			// directions are averaged as doubled angles, so opposite
+			// directions agree
+			sin, cos := math.Sincos(2 * p.Direction)
+			dx += cos
+			dy += sin
+		}
+		bounds = bounds.Intersect(c.img.Bounds())
+		if bounds.Empty() {
+			continue
+		}
+		n := float64(len(run))
+		density := t.density(pressure/n, speed/n)
+		opacity := run[len(run)-1].Opacity
+		sin, cos := math.Sincos(math.Atan2(dy, dx) / 2)
+
+		mask := image.NewAlpha(bounds)
+		c.outline(bounds, points)
+		c.r.Draw(mask, bounds, image.Opaque, image.Point{})
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				i := mask.PixOffset(x, y)
+				if mask.Pix[i] == 0 {
+					continue
+				}
+				px := (float64(x) + 0.5 - c.originX) / c.scale
+				py := (float64(y) + 0.5 - c.originY) / c.scale
+				u := (px*cos + py*sin) / t.along
+				v := (py*cos - px*sin) / t.across
+				noise := 0.65*c.grain.at(seed, u, v) + 0.35*c.grain.at(seed+1, u*2.7, v*2.7)
+				a := uint8(float64(mask.Pix[i])*opacity*smoothstep(1-density-soft, 1-density+soft, noise) + 0.5)
+				if j := ink.PixOffset(x, y); a > ink.Pix[j] {
+					ink.Pix[j] = a
+				}
+			}
+		}
+	}
+	col := s.Color
+	col.A = 0xff
+	draw.DrawMask(c.img, area, image.NewUniform(col), image.Point{}, ink, area.Min, draw.Over)
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// smoothstep is 0 below edge0, 1 above edge1 and smooth in between
+func smoothstep(edge0, edge1, x float64) float64 {
+	t := clamp((x-edge0)/(edge1-edge0), 0, 1)
+	return t * t * (3 - 2*t)
+}
@@ -0,0 +1,36 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// the grain comes from the seed, the same seed draws the same image
+func TestTextureSeed(t *testing.T) {
+	scene := testScene(
+		testLine(v6.ToolPencil, 20, 20, 180, 60, 100, 180),
+		testLine(v6.ToolPaintbrush, 20, 180, 180, 140, 100, 20),
+	)
+	frame := &v6.Rect{MaxX: 200, MaxY: 200}
+	encode := func(opts *Options) []byte {
+		var out bytes.Buffer
+		opts.Frame = frame
+		if err := PNG(&out, scene, opts); err != nil {
+			t.Fatal(err)
+		}
+		return out.Bytes()
+	}
+
+	first := encode(&Options{Texture: true, Seed: 1})
+	if again := encode(&Options{Texture: true, Seed: 1}); !bytes.Equal(first, again) {
+		t.Error("the same seed draws a different image")
+	}
+	if other := encode(&Options{Texture: true, Seed: 2}); bytes.Equal(first, other) {
+		t.Error("another seed draws the same image")
+	}
+	if plain := encode(&Options{Seed: 1}); bytes.Equal(first, plain) {
+		t.Error("the texture is not drawn")
+	}
+}
@@ -0,0 +1,191 @@
+package render
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// size of the thumbnails the device keeps in .thumbnails
+const (
+	ThumbnailWidth  = 280
+	ThumbnailHeight = 374
+)
+
+const (
+	// space around the content of a thumbnail
+	thumbnailMargin = 50
+	// the content is not cropped smaller, a few strokes would be blown up
+	thumbnailMinCrop = PageWidth / 4
+)
+
+// thumbnailFrame is the content of the page with a margin, the whole page
+// when it is empty
+func thumbnailFrame(scene *v6.Scene, opts *Options) v6.Rect {
+	bounds := CropFrame(scene, opts, thumbnailMargin)
+	grow := func(min, max float32) (float32, float32) {
+		if missing := thumbnailMinCrop - (max - min); missing > 0 {
+			min, max = min-missing/2, max+missing/2
+		}
+		return min, max
+	}
+	bounds.MinX, bounds.MaxX = grow(bounds.MinX, bounds.MaxX)
+	bounds.MinY, bounds.MaxY = grow(bounds.MinY, bounds.MaxY)
+	return bounds
+}
+
+// Thumbnail renders the content of the page as large as it fits into width
+// by height, the aspect ratio is kept
+func Thumbnail(scene *v6.Scene, opts *Options, width, height int) image.Image {
+	if opts == nil {
+		opts = &Options{}
+	}
+	scene, opts = opts.selected(scene)
+	frame := thumbnailFrame(scene, opts)
+	scale := math.Min(float64(width)/float64(frame.Width()), float64(height)/float64(frame.Height()))
+	thumbOpts := *opts
+	thumbOpts.Frame = &frame
+	thumbOpts.DPI = DPI * scale
+	thumbOpts.Display = nil
+	thumbOpts.Split = false
+	return Raster(scene, &thumbOpts)
+}
+
+// ErrNoThumbnailDir is returned by a ThumbnailCache without a directory
+var ErrNoThumbnailDir = errors.New("the thumbnail cache has no directory")
+
+// ThumbnailCache keeps the thumbnails in a directory, by the hash of the
+// .rm file and of the options. A page that didn't change is not rendered
+// again.
+type ThumbnailCache struct {
+	// Dir of the cache, it has to be set
+	Dir     string
+	Options *Options
+	// Width and Height of the thumbnails, the size of the device when not set
+	Width, Height int
+}
+
+func (c *ThumbnailCache) size() (int, int) {
+	if c.Width <= 0 || c.Height <= 0 {
+		return ThumbnailWidth, ThumbnailHeight
+	}
+	return c.Width, c.Height
+}
+
+// path of the thumbnail of the page in the cache
+func (c *ThumbnailCache) path(data []byte) string {
+	h := sha256.New()
+	h.Write(data)
+	writeThumbnailOptions(h, c.Options)
+	width, height := c.size()
+	return filepath.Join(c.Dir, fmt.Sprintf("%s-%dx%d.png", hex.EncodeToString(h.Sum(nil)), width, height))
+}
+
+// writeThumbnailOptions writes the options that change a thumbnail in a
+// fixed order, the frame, the resolution and the display are set by
+// Thumbnail. Maps are printed with sorted keys.
+func writeThumbnailOptions(w io.Writer, opts *Options) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	fmt.Fprintf(w, "\nhidden:%t texture:%t seed:%d debug:%t\n",
+		opts.HiddenLayers, opts.Texture, opts.Seed, opts.Debug)
+	fmt.Fprintf(w, "palette:%+v\n", *opts.palette())
+	if opts.Filter != nil {
+		fmt.Fprintf(w, "filter:%+v\n", *opts.Filter)
+	}
+	if t := opts.Template; t != nil {
+		fmt.Fprintf(w, "template:%q svg:%d\n", t.Name, len(t.SVG))
+		w.Write(t.SVG)
+		if t.Image != nil {
+			bounds := t.Image.Bounds()
+			fmt.Fprintf(w, "image:%v\n", bounds)
+			row := make([]byte, 0, 4*bounds.Dx())
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				row = row[:0]
+				for x := bounds.Min.X; x < bounds.Max.X; x++ {
+					r, g, b, a := t.Image.At(x, y).RGBA()
+					row = append(row, byte(r>>8), byte(g>>8), byte(b>>8), byte(a>>8))
+				}
+				w.Write(row)
+			}
+		}
+	}
+}
+
+// Get returns the png of the thumbnail of the page, data is the .rm file
+func (c *ThumbnailCache) Get(data []byte) ([]byte, error) {
+	if c.Dir == "" {
+		return nil, ErrNoThumbnailDir
+	}
+	name := c.path(data)
+	if cached, err := os.ReadFile(name); err == nil {
+		return cached, nil
+	}
+	scene, err := v6.ReadScene(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	width, height := c.size()
+	var thumbnail bytes.Buffer
+	if err := png.Encode(&thumbnail, Thumbnail(&scene, c.Options, width, height)); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(name, thumbnail.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+	return thumbnail.Bytes(), nil
+}
+
+// RefreshDocument writes the thumbnails of the pages of a document that
+// are missing or older than their page. The document is laid out as on
+// the device, dir holds the .rm files and dir.thumbnails the thumbnails.
+// The names of the thumbnails that were written are returned.
+func (c *ThumbnailCache) RefreshDocument(dir string) (updated []string, err error) {
+	dir = filepath.Clean(dir)
+	pages, err := filepath.Glob(filepath.Join(dir, "*.rm"))
+	if err != nil {
+		return
+	}
+	thumbnails := dir + ".thumbnails"
+	for _, page := range pages {
+		info, err := os.Stat(page)
+		if err != nil {
+			return updated, err
+		}
+		name := filepath.Join(thumbnails, strings.TrimSuffix(filepath.Base(page), ".rm")+".png")
+		if thumb, err := os.Stat(name); err == nil && !thumb.ModTime().Before(info.ModTime()) {
+			continue
+		}
+		data, err := os.ReadFile(page)
+		if err != nil {
+			return updated, err
+		}
+		thumbnail, err := c.Get(data)
+		if err != nil {
+			return updated, fmt.Errorf("%s: %w", page, err)
+		}
+		if err := os.MkdirAll(thumbnails, 0755); err != nil {
+			return updated, err
+		}
+		if err := os.WriteFile(name, thumbnail, 0644); err != nil {
+			return updated, err
+		}
+		updated = append(updated, name)
+	}
+	return
+}
@@ -0,0 +1,82 @@
+package render
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pages that differ only in the options are cached apart
+func TestThumbnailCache(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "notebooks", "v6_text.rm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	options := []*Options{
+		nil,
+		{},
+		{Filter: &Filter{NoText: true}},
+		{Filter: &Filter{ShowErased: true}},
+		{Palette: DarkPalette},
+		{Texture: true, Seed: 1},
+		{Texture: true, Seed: 2},
+	}
+	for _, opts := range options {
+		c := &ThumbnailCache{Dir: dir, Options: opts}
+		for i := 0; i < 2; i++ {
+			if _, err := c.Get(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no options are the zero options
+	if len(entries) != len(options)-1 {
+		t.Errorf("%d thumbnails cached, want %d", len(entries), len(options)-1)
+	}
+
+	c := &ThumbnailCache{}
+	if _, err := c.Get(data); !errors.Is(err, ErrNoThumbnailDir) {
+		t.Errorf("error %v without a directory, want %v", err, ErrNoThumbnailDir)
+	}
+}
+
+func TestThumbnailSize(t *testing.T) {
+	for _, name := range notebooks {
+		bounds := Thumbnail(readNotebook(t, name), nil, ThumbnailWidth, ThumbnailHeight).Bounds()
+		if bounds.Dx() > ThumbnailWidth || bounds.Dy() > ThumbnailHeight ||
+			bounds.Dx() != ThumbnailWidth && bounds.Dy() != ThumbnailHeight {
+			t.Errorf("%s: %v, want it to fit %dx%d", name, bounds, ThumbnailWidth, ThumbnailHeight)
+		}
+	}
+}
+
+func TestRefreshDocument(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "notebooks", "migration_v6.rm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	document := filepath.Join(t.TempDir(), "document")
+	if err := os.Mkdir(document, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(document, "page.rm"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := &ThumbnailCache{Dir: t.TempDir()}
+	updated, err := c.RefreshDocument(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(document+".thumbnails", "page.png"); len(updated) != 1 || updated[0] != want {
+		t.Errorf("updated %v, want %s", updated, want)
+	}
+	if updated, err := c.RefreshDocument(document); err != nil || len(updated) != 0 {
+		t.Errorf("up to date thumbnails written again %v: %v", updated, err)
+	}
+}
@@ -0,0 +1,244 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+
+	v6 "github.com/ddvk/reader/v6"
+	"golang.org/x/image/vector"
+)
+
+// TileOptions is the layout of a tile pyramid
+type TileOptions struct {
+	// Size of the tiles in pixels, without the overlap
+	Size int
+	// Overlap of neighbouring tiles in pixels, deep zoom viewers expect 1
+	Overlap int
+	// Format of the tiles, png or jpg
+	Format string
+}
+
+var (
+	// DefaultDeepZoom the tiles of Deep Zoom Composer
+	DefaultDeepZoom = &TileOptions{Size: 254, Overlap: 1, Format: "png"}
+	// DefaultXYZ the tiles of web maps
+	DefaultXYZ = &TileOptions{Size: 256, Format: "png"}
+)
+
+// pyramid renders the tiles of a scene, the highest level is the frame at
+// the resolution of the options and every level below is half the size
+type pyramid struct {
+	scene *v6.Scene
+	opts  *Options
+	tiles *TileOptions
+	frame v6.Rect
+	scale float64
+	// size of the highest level in pixels
+	width, height int
+	maxLevel      int
+	// bounds of the lines with their width, to find the lines of a tile
+	bounds map[*v6.LineItem]v6.Rect
+}
+
+func newPyramid(scene *v6.Scene, opts *Options, tiles *TileOptions) *pyramid {
+	if opts == nil {
+		opts = &Options{}
+	}
+	scene, opts = opts.selected(scene)
+	frame := opts.frame(scene)
+	p := &pyramid{
+		scene:  scene,
+		opts:   opts,
+		tiles:  tiles,
+		frame:  frame,
+		scale:  opts.scale(),
+		bounds: make(map[*v6.LineItem]v6.Rect),
+	}
+	p.width = pixels(float64(frame.Width()) * p.scale)
+	p.height = pixels(float64(frame.Height()) * p.scale)
+	size := p.width
+	if p.height > size {
+		size = p.height
+	}
+	p.maxLevel = int(math.Ceil(math.Log2(float64(size))))
+
+	for _, layer := range scene.Layers {
+		for _, line := range layer.Lines {
+			s := newStroke(line, opts.palette())
+			if s == nil {
+				continue
+			}
+			bounds := v6.EmptyRect
+			for _, point := range s.Points {
+				r := float32(point.Width / 2)
+				bounds = bounds.Union(v6.Rect{
+					MinX: float32(point.X) - r, MinY: float32(point.Y) - r,
+					MaxX: float32(point.X) + r, MaxY: float32(point.Y) + r,
+				})
+			}
+			p.bounds[line] = bounds
+		}
+	}
+	return p
+}
+
+// levelSize is the size of the image at the level in pixels
+func (p *pyramid) levelSize(level int) (width, height int) {
+	factor := math.Pow(2, float64(p.maxLevel-level))
+	return int(math.Ceil(float64(p.width) / factor)), int(math.Ceil(float64(p.height) / factor))
+}
+
+// columns and rows of tiles at the level
+func (p *pyramid) grid(level int) (columns, rows int) {
+	width, height := p.levelSize(level)
+	size := p.tiles.Size
+	return (width + size - 1) / size, (height + size - 1) / size
+}
+
+// tileBounds is the area of the tile in the image of the level, with the
+// overlap on the sides that have a neighbour
+func (p *pyramid) tileBounds(level, column, row int) image.Rectangle {
+	width, height := p.levelSize(level)
+	size, overlap := p.tiles.Size, p.tiles.Overlap
+	r := image.Rect(column*size-overlap, row*size-overlap, (column+1)*size+overlap, (row+1)*size+overlap)
+	return r.Intersect(image.Rect(0, 0, width, height))
+}
+
+// render draws the tile, only the lines that touch it are drawn
+func (p *pyramid) render(level int, r image.Rectangle) *image.RGBA {
+	scale := p.scale / math.Pow(2, float64(p.maxLevel-level))
+	tileOpts := *p.opts
+	tileOpts.DPI = DPI * scale
+	tileOpts.Display = nil
+	frame := v6.Rect{
+		MinX: p.frame.MinX + float32(float64(r.Min.X)/scale),
+		MinY: p.frame.MinY + float32(float64(r.Min.Y)/scale),
+		MaxX: p.frame.MinX + float32(float64(r.Max.X)/scale),
+		MaxY: p.frame.MinY + float32(float64(r.Max.Y)/scale),
+	}
+	tileOpts.Frame = &frame
+
+	// the image is the tile in the image of the level, so the tiles meet
+	// at whole pixels
+	c := &rasterCanvas{
+		img:     image.NewRGBA(r),
+		scale:   scale,
+		originX: -float64(p.frame.MinX) * scale,
+		originY: -float64(p.frame.MinY) * scale,
+		r:       vector.NewRasterizer(0, 0),
+		grain:   newGrain(&tileOpts),
+	}
+	draw.Draw(c.img, r, image.NewUniform(tileOpts.palette().Background), image.Point{}, draw.Src)
+	drawScene(c, p.visible(frame), frame, &tileOpts)
+	return c.img
+}
+
+// visible is the scene with only the lines that touch the frame
+func (p *pyramid) visible(frame v6.Rect) *v6.Scene {
+	scene := *p.scene
+	scene.Layers = make([]*v6.Layer, len(p.scene.Layers))
+	for i, layer := range p.scene.Layers {
+		visible := *layer
+		visible.Lines = nil
+		for _, line := range layer.Lines {
+			if bounds, ok := p.bounds[line]; ok && bounds.Intersects(frame) {
+				visible.Lines = append(visible.Lines, line)
+			}
+		}
+		scene.Layers[i] = &visible
+	}
+	return &scene
+}
+
+// writeTile renders the tile into the file, the directories are created
+func (p *pyramid) writeTile(name string, level int, r image.Rectangle) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	img := p.render(level, r)
+	switch p.tiles.Format {
+	case "png":
+		err = png.Encode(out, img)
+	case "jpg", "jpeg":
+		quality := defaultQuality
+		if p.opts.Quality > 0 {
+			quality = p.opts.Quality
+		}
+		err = jpeg.Encode(out, img, &jpeg.Options{Quality: quality})
+	default:
+		err = fmt.Errorf("unknown tile format: %s", p.tiles.Format)
+	}
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// DeepZoom writes the scene as a Deep Zoom image, name.dzi and the tiles in
+// name_files/level/column_row.png. Level 0 is a single pixel.
+func DeepZoom(dir, name string, scene *v6.Scene, opts *Options, tiles *TileOptions) error {
+	if tiles == nil {
+		tiles = DefaultDeepZoom
+	}
+	p := newPyramid(scene, opts, tiles)
+	for level := 0; level <= p.maxLevel; level++ {
+		columns, rows := p.grid(level)
+		for column := 0; column < columns; column++ {
+			for row := 0; row < rows; row++ {
+				file := filepath.Join(dir, name+"_files", fmt.Sprint(level), fmt.Sprintf("%d_%d.%s", column, row, tiles.Format))
+				if err := p.writeTile(file, level, p.tileBounds(level, column, row)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	descriptor := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Image xmlns="http://schemas.microsoft.com/deepzoom/2008" Format="%s" Overlap="%d" TileSize="%d">
+  <Size Width="%d" Height="%d"/>
+</Image>
+`, tiles.Format, tiles.Overlap, tiles.Size, p.width, p.height)
+	return os.WriteFile(filepath.Join(dir, name+".dzi"), []byte(descriptor), 0644)
+}
+
+// XYZ writes the scene as tiles in dir/z/x/y.png. Zoom 0 is the whole frame
+// in one tile and every zoom doubles the size up to the resolution of the
+// options, the layout of CRS.Simple in leaflet.
+func XYZ(dir string, scene *v6.Scene, opts *Options, tiles *TileOptions) error {
+	if tiles == nil {
+		tiles = DefaultXYZ
+	}
+	p := newPyramid(scene, opts, tiles)
+	minLevel := 0
+	for level := p.maxLevel; level >= 0; level-- {
+		if columns, rows := p.grid(level); columns <= 1 && rows <= 1 {
+			minLevel = level
+			break
+		}
+	}
+	for level := minLevel; level <= p.maxLevel; level++ {
+		columns, rows := p.grid(level)
+		for x := 0; x < columns; x++ {
+			for y := 0; y < rows; y++ {
+				// the tiles at the edges are not cut, viewers expect tiles of
+				// the same size
+				r := image.Rect(x*tiles.Size, y*tiles.Size, (x+1)*tiles.Size, (y+1)*tiles.Size)
+				file := filepath.Join(dir, fmt.Sprint(level-minLevel), fmt.Sprint(x), fmt.Sprintf("%d.%s", y, tiles.Format))
+				if err := p.writeTile(file, level, r); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"image"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	v6 "github.com/ddvk/reader/v6"
+)
+
+// tileScene is 600x300 pixels at the resolution of the device
+func tileScene() (*v6.Scene, *Options) {
+	scene := testScene(testLine(v6.ToolFineliner, 10, 10, 590, 290))
+	return scene, &Options{Frame: &v6.Rect{MinX: 0, MinY: 0, MaxX: 600, MaxY: 300}, DPI: DPI}
+}
+
+func tileSize(t *testing.T, name string) image.Point {
+	t.Helper()
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return image.Pt(config.Width, config.Height)
+}
+
+func countTiles(t *testing.T, pattern string) int {
+	t.Helper()
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(files)
+}
+
+func TestDeepZoom(t *testing.T) {
+	dir := t.TempDir()
+	scene, opts := tileScene()
+	if err := DeepZoom(dir, "page", scene, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	// 600 pixels need 10 halvings to get to one pixel, the tiles of 254
+	// pixels cover the levels of 600x300 and 300x150
+	for level := 0; level <= 10; level++ {
+		want := 1
+		switch level {
+		case 10:
+			want = 6
+		case 9:
+			want = 2
+		}
+		if got := countTiles(t, filepath.Join(dir, "page_files", strconv.Itoa(level), "*.png")); got != want {
+			t.Errorf("level %d: %d tiles, want %d", level, got, want)
+		}
+	}
+	if got := countTiles(t, filepath.Join(dir, "page_files", "11", "*")); got != 0 {
+		t.Errorf("%d tiles above the highest level", got)
+	}
+
+	// the tiles overlap where they have a neighbour
+	tiles := []struct {
+		name string
+		size image.Point
+	}{
+		{"10/0_0.png", image.Pt(255, 255)},
+		{"10/1_0.png", image.Pt(256, 255)},
+		{"10/2_1.png", image.Pt(600-507, 300-253)},
+		{"9/1_0.png", image.Pt(300-253, 150)},
+		{"0/0_0.png", image.Pt(1, 1)},
+	}
+	for _, tile := range tiles {
+		if size := tileSize(t, filepath.Join(dir, "page_files", tile.name)); size != tile.size {
+			t.Errorf("%s: %v, want %v", tile.name, size, tile.size)
+		}
+	}
+
+	descriptor, err := os.ReadFile(filepath.Join(dir, "page.dzi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(descriptor), `<Size Width="600" Height="300"/>`) {
+		t.Errorf("descriptor %s", descriptor)
+	}
+}
+
+func TestXYZ(t *testing.T) {
+	dir := t.TempDir()
+	scene, opts := tileScene()
+	if err := XYZ(dir, scene, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	// zoom 0 is the level of 150x75, the first that fits one tile
+	for zoom, want := range []int{1, 2, 6} {
+		if got := countTiles(t, filepath.Join(dir, strconv.Itoa(zoom), "*", "*.png")); got != want {
+			t.Errorf("zoom %d: %d tiles, want %d", zoom, got, want)
+		}
+	}
+	if got := countTiles(t, filepath.Join(dir, "3")); got != 0 {
+		t.Error("tiles above the resolution of the options")
+	}
+	for _, name := range []string{"0/0/0.png", "2/2/1.png"} {
+		if size := tileSize(t, filepath.Join(dir, name)); size != image.Pt(256, 256) {
+			t.Errorf("%s: %v, want all tiles of the same size", name, size)
+		}
+	}
+
+	if err := XYZ(dir, scene, opts, &TileOptions{Size: 256, Format: "tiff"}); err == nil {
+		t.Error("no error for an unknown format")
+	}
+}
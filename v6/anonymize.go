@@ -0,0 +1,201 @@
+package v6
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Anonymizer removes personal data from a scene but keeps the binary layout,
+// every block keeps its size so the file can still be used to show a bug
+type Anonymizer struct {
+	// Noise is the maximal distance a point is moved in each direction
+	Noise float32
+	// Seed makes the output reproducible
+	Seed int64
+
+	rand *rand.Rand
+}
+
+// Anonymize replaces the author uuids and the migration id, scrambles the
+// text and moves the points by a random amount. An error is returned when
+// a uuid can't be found in the block of the uuids.
+func (a *Anonymizer) Anonymize(scene *Scene) (err error) {
+	a.rand = rand.New(rand.NewSource(a.Seed))
+	for _, block := range scene.Blocks {
+		switch block.Header.Info.PayloadType {
+		case InfoTag:
+			scene.MigrationInfo.MigrationId = a.randomId(scene.MigrationInfo.MigrationId)
+			e := NewEncoder()
+			e.WriteMigrationInfo(scene.MigrationInfo)
+			block.Payload = e.Bytes()
+		case UUIDIdexTag:
+			block.Payload, err = a.replaceUUIDs(&scene.UUIDMap, block.Payload)
+			if err != nil {
+				return
+			}
+		}
+
+		switch v := block.Item.(type) {
+		case *LineItem:
+			a.movePoints(v)
+		case *GlyphRange:
+			block.Payload = a.replaceHighlight(v, block.Payload)
+		case *SceneTextItem:
+			for _, item := range v.Sequence.Container {
+				item.Value.Text = a.scramble(item.Value.Text)
+			}
+			v.IsDirty = true
+		}
+	}
+	return
+}
+
+// randomId keeps the author and the encoded size of the counter
+func (a *Anonymizer) randomId(id CrdtId) CrdtId {
+	counter := id.Counter()
+	if counter == 0 {
+		return id
+	}
+	var low, high uint64 = 1, 0x7f
+	for counter > high {
+		low = high + 1
+		high = high<<7 | 0x7f
+	}
+	return NewCrdtId(id.Author(), low+uint64(a.rand.Int63n(int64(high-low+1))))
+}
+
+func (a *Anonymizer) replaceUUIDs(uuidMap *UUIDMap, payload []byte) ([]byte, error) {
+	var indexes []AuthorId
+	for index := range uuidMap.Index2UUID {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i] < indexes[j]
+	})
+
+	replaced := NewMap()
+	for _, index := range indexes {
+		old := uuidMap.Index2UUID[index]
+		var u uuid.UUID
+		a.rand.Read(u[:])
+		// random uuid, version 4
+		u[6] = u[6]&0x0f | 0x40
+		u[8] = u[8]&0x3f | 0x80
+		if !bytes.Contains(payload, old[:]) {
+			return nil, fmt.Errorf("uuid %v of author %d not found", old, index)
+		}
+		payload = bytes.Replace(payload, old[:], u[:], 1)
+		replaced.Add(u, index)
+	}
+	*uuidMap = replaced
+	return payload, nil
+}
+
+func (a *Anonymizer) movePoints(line *LineItem) {
+	for _, p := range line.Line.Value.Points {
+		p.X += (a.rand.Float32()*2 - 1) * a.Noise
+		p.Y += (a.rand.Float32()*2 - 1) * a.Noise
+	}
+	line.Line.Value.UpdateBoundingRect()
+	line.IsDirty = true
+}
+
+// replaceHighlight patches the text in place, highlights are not encoded
+func (a *Anonymizer) replaceHighlight(highlight *GlyphRange, payload []byte) []byte {
+	if highlight.Text == "" {
+		return payload
+	}
+	text := a.scramble(highlight.Text)
+	old, updated := NewEncoder(), NewEncoder()
+	old.PutString(5, highlight.Text)
+	updated.PutString(5, text)
+	highlight.Text = text
+	return bytes.Replace(payload, old.Bytes(), updated.Bytes(), 1)
+}
+
+// scramble replaces letters and digits by random ones of the same
+// kind that need the same number of bytes
+func (a *Anonymizer) scramble(text string) string {
+	buffer := make([]byte, 0, len(text))
+	for _, r := range text {
+		buffer = utf8.AppendRune(buffer, a.scrambleRune(r))
+	}
+	return string(buffer)
+}
+
+func (a *Anonymizer) scrambleRune(r rune) rune {
+	pick := func(from, to rune) rune {
+		return from + rune(a.rand.Intn(int(to-from+1)))
+	}
+	switch {
+	case r >= 'a' && r <= 'z':
+		return pick('a', 'z')
+	case r >= 'A' && r <= 'Z':
+		return pick('A', 'Z')
+	case r >= '0' && r <= '9':
+		return pick('0', '9')
+	case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+		return r
+	}
+	switch utf8.RuneLen(r) {
+	case 2:
+		// cyrillic
+		if unicode.IsUpper(r) {
+			return pick('А', 'Я')
+		}
+		return pick('а', 'я')
+	case 3:
+		// cjk
+		return pick(0x4e00, 0x9fff)
+	default:
+		return pick(0x10400, 0x1044f)
+	}
+}
+
+// AnonymizeFile anonymizes a v6 file, the result is checked to have the
+// same blocks with the same sizes
+func AnonymizeFile(r io.Reader, w io.Writer, anonymizer Anonymizer) (err error) {
+	scene, err := ReadScene(r)
+	if err != nil {
+		return
+	}
+	var sizes []int32
+	for _, block := range scene.Blocks {
+		sizes = append(sizes, int32(len(block.Payload)))
+	}
+
+	err = anonymizer.Anonymize(&scene)
+	if err != nil {
+		return
+	}
+	var buffer bytes.Buffer
+	err = WriteScene(&buffer, &scene)
+	if err != nil {
+		return
+	}
+
+	data := buffer.Bytes()[len(HeaderV6):]
+	for i, size := range sizes {
+		var header Header
+		header, err = ReadHeader(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		if header.Size != size {
+			return fmt.Errorf("block %d changed size: %d, was: %d", i, header.Size, size)
+		}
+		data = data[8+int(size):]
+	}
+	if len(data) != 0 {
+		return fmt.Errorf("unexpected data after the last block: %d bytes", len(data))
+	}
+	_, err = w.Write(buffer.Bytes())
+	return
+}
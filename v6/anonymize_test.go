@@ -0,0 +1,62 @@
+package v6
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func sceneText(scene *Scene) (text string) {
+	if scene.Text == nil {
+		return
+	}
+	for _, item := range scene.Text.Sequence.Container {
+		text += item.Value.Text
+	}
+	return
+}
+
+func blockSizes(scene *Scene) (sizes []int) {
+	for _, block := range scene.Blocks {
+		sizes = append(sizes, len(block.Payload))
+	}
+	return
+}
+
+func TestAnonymizeFile(t *testing.T) {
+	for _, name := range notebooks {
+		data, scene := readNotebook(t, name)
+		var anonymized bytes.Buffer
+		if err := AnonymizeFile(bytes.NewReader(data), &anonymized, Anonymizer{Noise: 1, Seed: 1}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		result, err := ReadScene(bytes.NewReader(anonymized.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: anonymized file can't be read: %v", name, err)
+		}
+		if sizes, want := blockSizes(&result), blockSizes(&scene); !equalCounts(sizes, want) {
+			t.Errorf("%s: block sizes %v, want %v", name, sizes, want)
+		}
+		for index, u := range scene.UUIDMap.Index2UUID {
+			if bytes.Contains(anonymized.Bytes(), u[:]) {
+				t.Errorf("%s: uuid %v of author %d left in the file", name, u, index)
+			}
+		}
+		if text := sceneText(&scene); text != "" && sceneText(&result) == text {
+			t.Errorf("%s: text %q not changed", name, text)
+		}
+	}
+}
+
+func TestAnonymizeMissingUUID(t *testing.T) {
+	_, scene := readNotebook(t, "v6_text.rm")
+	for index := range scene.UUIDMap.Index2UUID {
+		// a uuid that is not in the block
+		scene.UUIDMap.Index2UUID[index] = uuid.UUID{1, 2, 3}
+	}
+	anonymizer := Anonymizer{Seed: 1}
+	if err := anonymizer.Anonymize(&scene); err == nil {
+		t.Error("no error for a uuid that is not in the file")
+	}
+}
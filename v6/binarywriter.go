@@ -0,0 +1,73 @@
+package v6
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// BinarySerializer is the counterpart of BinaryDeserializer
+type BinarySerializer struct {
+	buffer bytes.Buffer
+}
+
+func NewSerializer() *BinarySerializer {
+	return &BinarySerializer{}
+}
+
+// Pos current position in the stream
+func (s *BinarySerializer) Pos() int {
+	return s.buffer.Len()
+}
+
+func (s *BinarySerializer) Bytes() []byte {
+	return s.buffer.Bytes()
+}
+
+func (s *BinarySerializer) Write(b []byte) (n int, err error) {
+	return s.buffer.Write(b)
+}
+
+func (s *BinarySerializer) WriteByte(b byte) error {
+	return s.buffer.WriteByte(b)
+}
+
+func (s *BinarySerializer) PutBytes(b []byte) {
+	s.buffer.Write(b)
+}
+
+func (s *BinarySerializer) PutShort(val uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], val)
+	s.buffer.Write(b[:])
+}
+
+func (s *BinarySerializer) PutFloat32(val float32) {
+	s.PutFixedUInt32(math.Float32bits(val))
+}
+
+func (s *BinarySerializer) PutFloat64(val float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(val))
+	s.buffer.Write(b[:])
+}
+
+func (s *BinarySerializer) PutVarUInt32(val uint32) {
+	s.PutVarUInt64(uint64(val))
+}
+
+func (s *BinarySerializer) PutVarUInt64(val uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], val)
+	s.buffer.Write(b[:n])
+}
+
+func (s *BinarySerializer) PutFixedUInt32(val uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], val)
+	s.buffer.Write(b[:])
+}
+
+func (s *BinarySerializer) PutFixedInt32(val int32) {
+	s.PutFixedUInt32(uint32(val))
+}
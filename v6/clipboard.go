@@ -0,0 +1,87 @@
+package v6
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNoLayer = errors.New("no such layer")
+
+// layer returns the layer at the index, an error when there is none
+func (s *Scene) layer(index int) (*Layer, error) {
+	if index < 0 || index >= len(s.Layers) {
+		return nil, fmt.Errorf("%w: %d of %d", ErrNoLayer, index, len(s.Layers))
+	}
+	return s.Layers[index], nil
+}
+
+// NewId returns a fresh id that does not collide with anything in the scene
+func (s *Scene) NewId() CrdtId {
+	if s.NextItemId.Counter() == 0 {
+		s.NextItemId = NewCrdtId(LocalAuthor, 1)
+	}
+	id := s.NextItemId
+	s.NextItemId = NewCrdtId(LocalAuthor, id.Counter()+1)
+	return id
+}
+
+// Clone makes a deep copy of the line, points included
+func (t *LineItem) Clone() *LineItem {
+	c := *t
+	c.Bob = append([]byte(nil), t.Bob...)
+	c.Line.Value.Points = make([]*PenPoint, len(t.Line.Value.Points))
+	for i, p := range t.Line.Value.Points {
+		point := *p
+		c.Line.Value.Points[i] = &point
+	}
+	return &c
+}
+
+// Inside reports whether every point of the line lies in the shape
+func (t *LineItem) Inside(shape Shape) bool {
+	points := t.Line.Value.Points
+	if len(points) == 0 {
+		return false
+	}
+	for _, p := range points {
+		if !shape.Contains(p.X, p.Y) {
+			return false
+		}
+	}
+	return true
+}
+
+// Copy returns copies of the lines that lie completely inside the shape
+func (s *Scene) Copy(shape Shape) (lines []*LineItem) {
+	for _, layer := range s.Layers {
+		for _, line := range layer.Lines {
+			if line.Inside(shape) {
+				lines = append(lines, line.Clone())
+			}
+		}
+	}
+	return
+}
+
+// Paste adds the lines to the layer, moved by dx, dy, as new items
+func (s *Scene) Paste(layer int, lines []*LineItem, dx, dy float32) (pasted []*LineItem, err error) {
+	l, err := s.layer(layer)
+	if err != nil {
+		return
+	}
+	for _, line := range lines {
+		item := line.Clone()
+		item.Id = s.NewId()
+		item.ParentId = l.Id
+		item.Line.Timestamp = s.NewId()
+		item.IsDirty = true
+		for _, p := range item.Line.Value.Points {
+			p.X += dx
+			p.Y += dy
+		}
+		item.Line.Value.UpdateBoundingRect()
+		l.Lines = append(l.Lines, item)
+		pasted = append(pasted, item)
+	}
+	return
+}
@@ -0,0 +1,97 @@
+package v6
+
+import (
+	"errors"
+	"testing"
+)
+
+// testScene has one layer with a line along y=0 from 0 to 100, a point
+// every 10 units, and a line of two points along y=50
+func testScene() *Scene {
+	scene := &Scene{}
+	layer := &Layer{Id: NewCrdtId(0, 11)}
+	var points []*PenPoint
+	for x := 0; x <= 100; x += 10 {
+		points = append(points, &PenPoint{X: float32(x), Width: 8, Pressure: 100})
+	}
+	layer.Lines = append(layer.Lines,
+		testLine(scene, layer, points...),
+		testLine(scene, layer, &PenPoint{X: 0, Y: 50}, &PenPoint{X: 100, Y: 50}))
+	scene.Layers = append(scene.Layers, layer)
+	return scene
+}
+
+func testLine(scene *Scene, layer *Layer, points ...*PenPoint) *LineItem {
+	line := &LineItem{SceneItem: SceneItem{Id: scene.NewId(), ParentId: layer.Id, Type: LineType}}
+	line.Line.Value.Tool = ToolFineliner
+	line.Line.Value.Points = points
+	line.Line.Value.UpdateBoundingRect()
+	return line
+}
+
+func pointCounts(lines []*LineItem) (counts []int) {
+	for _, line := range lines {
+		counts = append(counts, len(line.Line.Value.Points))
+	}
+	return
+}
+
+func equalCounts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCopyPaste(t *testing.T) {
+	tests := []struct {
+		name   string
+		shape  Shape
+		layer  int
+		counts []int
+		err    error
+	}{
+		{"nothing", Rect{MinX: 200, MinY: 200, MaxX: 300, MaxY: 300}, 0, []int{11, 2}, nil},
+		{"partly inside", Rect{MinX: -5, MinY: -5, MaxX: 50, MaxY: 5}, 0, []int{11, 2}, nil},
+		{"one line", Rect{MinX: -5, MinY: -5, MaxX: 105, MaxY: 5}, 0, []int{11, 2, 11}, nil},
+		{"both lines", Rect{MinX: -5, MinY: -5, MaxX: 105, MaxY: 55}, 0, []int{11, 2, 11, 2}, nil},
+		{"missing layer", Rect{MinX: -5, MinY: -5, MaxX: 105, MaxY: 55}, 1, []int{11, 2}, ErrNoLayer},
+		{"negative layer", Rect{MinX: -5, MinY: -5, MaxX: 105, MaxY: 55}, -1, []int{11, 2}, ErrNoLayer},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scene := testScene()
+			copied := scene.Copy(test.shape)
+			pasted, err := scene.Paste(test.layer, copied, 10, 200)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("error %v, want %v", err, test.err)
+			}
+			if counts := pointCounts(scene.Layers[0].Lines); !equalCounts(counts, test.counts) {
+				t.Errorf("points of the lines %v, want %v", counts, test.counts)
+			}
+			ids := make(map[CrdtId]bool)
+			for _, line := range scene.Layers[0].Lines {
+				if ids[line.Id] {
+					t.Errorf("id %v used twice", line.Id)
+				}
+				ids[line.Id] = true
+			}
+			for i, line := range pasted {
+				original := copied[i].Line.Value.Points
+				for j, p := range line.Line.Value.Points {
+					if p.X != original[j].X+10 || p.Y != original[j].Y+200 {
+						t.Errorf("pasted point %v,%v, want %v,%v", p.X, p.Y, original[j].X+10, original[j].Y+200)
+					}
+				}
+				if r := line.Line.Value.BoundingRect; r.Min.Y < 190 {
+					t.Errorf("bounding rect %v not moved", r)
+				}
+			}
+		})
+	}
+}
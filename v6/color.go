@@ -0,0 +1,70 @@
+package v6
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PenColor the color of a line or a highlight as chosen on the device
+type PenColor byte
+
+// colors of the device, the highlight colors and the second set of colors
+// came with newer firmware
+const (
+	ColorBlack       PenColor = 0
+	ColorGray        PenColor = 1
+	ColorWhite       PenColor = 2
+	ColorYellow      PenColor = 3
+	ColorGreen       PenColor = 4
+	ColorPink        PenColor = 5
+	ColorBlue        PenColor = 6
+	ColorRed         PenColor = 7
+	ColorGrayOverlap PenColor = 8
+	ColorHighlight   PenColor = 9
+	ColorGreen2      PenColor = 10
+	ColorCyan        PenColor = 11
+	ColorMagenta     PenColor = 12
+	ColorYellow2     PenColor = 13
+)
+
+var colorNames = []string{
+	"black",
+	"gray",
+	"white",
+	"yellow",
+	"green",
+	"pink",
+	"blue",
+	"red",
+	"gray overlap",
+	"highlight",
+	"green2",
+	"cyan",
+	"magenta",
+	"yellow2",
+}
+
+func (c PenColor) String() string {
+	if int(c) < len(colorNames) {
+		return colorNames[c]
+	}
+	return fmt.Sprintf("color %d", byte(c))
+}
+
+// ParsePenColor returns the color with the name, case is ignored
+func ParsePenColor(name string) (PenColor, bool) {
+	for i, n := range colorNames {
+		if strings.EqualFold(n, name) {
+			return PenColor(i), true
+		}
+	}
+	return 0, false
+}
+
+// ARGB a color with alpha in the high byte, used by the shader
+type ARGB uint32
+
+// Components splits the color in its 8 bit components, not premultiplied
+func (c ARGB) Components() (r, g, b, a uint8) {
+	return uint8(c >> 16), uint8(c >> 8), uint8(c), uint8(c >> 24)
+}
@@ -0,0 +1,237 @@
+package v6
+
+import (
+	"math"
+	"unicode/utf8"
+)
+
+// Encoder writes tagged values, the counterpart of Extractor
+type Encoder struct {
+	s *BinarySerializer
+}
+
+func NewEncoder() *Encoder {
+	return &Encoder{
+		s: NewSerializer(),
+	}
+}
+
+// Bytes the encoded payload
+func (e *Encoder) Bytes() []byte {
+	return e.s.Bytes()
+}
+
+func (e *Encoder) PutTag(index TagIndex, tag ElementTag) {
+	e.s.PutVarUInt32(uint32(index)<<4 | uint32(tag))
+}
+
+// PutSubBlock writes a length prefixed block with the content written by f
+func (e *Encoder) PutSubBlock(index TagIndex, f func(e *Encoder)) {
+	sub := NewEncoder()
+	f(sub)
+	e.PutTag(index, Length4)
+	e.s.PutFixedUInt32(uint32(sub.s.Pos()))
+	e.s.PutBytes(sub.Bytes())
+}
+
+func (e *Encoder) PutUInt(index TagIndex, val uint32) {
+	e.PutTag(index, Length4)
+	e.s.PutFixedUInt32(val)
+}
+
+func (e *Encoder) PutInt(index TagIndex, val int) {
+	e.PutTag(index, Byte4)
+	e.s.PutFixedInt32(int32(val))
+}
+
+func (e *Encoder) PutShort(index TagIndex, val uint16) {
+	e.PutTag(index, Byte2)
+	e.s.PutShort(val)
+}
+
+func (e *Encoder) PutDouble(index TagIndex, val float64) {
+	e.PutTag(index, Byte8)
+	e.s.PutFloat64(val)
+}
+
+func (e *Encoder) PutFloat(index TagIndex, val float32) {
+	e.PutTag(index, Byte4)
+	e.s.PutFloat32(val)
+}
+
+func (e *Encoder) PutBool(index TagIndex, val bool) {
+	var b byte
+	if val {
+		b = 1
+	}
+	e.PutByte(index, b)
+}
+
+func (e *Encoder) PutByte(index TagIndex, val byte) {
+	e.PutTag(index, Byte1)
+	e.s.WriteByte(val)
+}
+
+func (e *Encoder) PutCrdtId(index TagIndex, id CrdtId) {
+	e.PutTag(index, CrdtTag)
+	e.putRawCrdtId(id)
+}
+
+func (e *Encoder) putRawCrdtId(id CrdtId) {
+	e.s.PutVarUInt32(uint32(id.Author()))
+	e.s.PutVarUInt64(id.Counter())
+}
+
+// putRawString writes the string content without a tag
+func (e *Encoder) putRawString(str string) {
+	e.s.PutVarUInt32(uint32(len(str)))
+	// the flag is named isAscii, the device sets it for any utf-8 text
+	var isAscii byte = 1
+	if !utf8.ValidString(str) {
+		isAscii = 0
+	}
+	e.s.WriteByte(isAscii)
+	e.s.PutBytes([]byte(str))
+}
+
+func (e *Encoder) PutString(index TagIndex, str string) {
+	e.PutSubBlock(index, func(e *Encoder) {
+		e.putRawString(str)
+	})
+}
+
+func (e *Encoder) PutPointV2(point *PenPoint) {
+	e.s.PutFloat32(point.X)
+	e.s.PutFloat32(point.Y)
+	e.s.PutShort(point.Speed)
+	e.s.PutShort(point.Width)
+	e.s.WriteByte(point.Direction)
+	e.s.WriteByte(point.Pressure)
+}
+
+func (e *Encoder) PutPointV1(point *PenPoint) {
+	e.s.PutFloat32(point.X)
+	e.s.PutFloat32(point.Y)
+	e.s.PutFloat32(float32(point.Speed) / 4)
+	e.s.PutFloat32(float32(float64(point.Direction) * math.Pi * 2 / 255))
+	e.s.PutFloat32(float32(point.Width) / 4)
+	e.s.PutFloat32(float32(point.Pressure) / 255)
+}
+
+// PutLine writes the line value, the point version is taken from the item
+func (e *Encoder) PutLine(item *LineItem) {
+	line := &item.Line.Value
+	e.PutInt(1, int(line.Tool))
+	e.PutInt(2, int(line.Color))
+	e.PutDouble(3, line.ThicknessScale)
+	e.PutFloat(4, line.StartingLength)
+	e.PutSubBlock(5, func(e *Encoder) {
+		putPoint := e.PutPointV2
+		if item.Info.CurrentVersion <= PointVersion1 {
+			putPoint = e.PutPointV1
+		}
+		for _, point := range line.Points {
+			putPoint(point)
+		}
+	})
+	e.PutCrdtId(6, item.Line.Timestamp)
+	if line.MoveId != 0 {
+		e.PutCrdtId(7, line.MoveId)
+	}
+	if line.ARGB != 0 {
+		e.PutInt(8, int(int32(line.ARGB)))
+	}
+}
+
+func (e *Encoder) PutSceneItem(index TagIndex, sceneItem SceneBaseItem) {
+	e.PutSubBlock(index, func(e *Encoder) {
+		item := sceneItem.Item()
+		e.s.WriteByte(byte(item.Type))
+		switch v := sceneItem.(type) {
+		case *LineItem:
+			e.PutLine(v)
+		}
+		e.s.PutBytes(item.Bob)
+	})
+}
+
+// WriteSceneItem writes the payload of a scene item block, a tombstone when
+// the item is deleted
+func (e *Encoder) WriteSceneItem(sceneItem SceneBaseItem) {
+	item := sceneItem.Item()
+	e.PutCrdtId(1, item.ParentId)
+	e.PutCrdtId(2, item.Id)
+	e.PutCrdtId(3, item.Left)
+	e.PutCrdtId(4, item.Right)
+	e.PutInt(5, item.DeletedLength)
+	if item.IsDeleted() {
+		return
+	}
+	e.PutSceneItem(6, sceneItem)
+}
+
+func (e *Encoder) PutTextItem(item *Item[TextItem]) {
+	e.PutSubBlock(0, func(e *Encoder) {
+		e.PutCrdtId(2, item.Id)
+		e.PutCrdtId(3, item.Left)
+		e.PutCrdtId(4, item.Right)
+		e.PutInt(5, item.DeletedLength)
+		if item.DeletedLength == 0 {
+			e.PutSubBlock(6, func(e *Encoder) {
+				e.putRawString(item.Value.Text)
+				if item.Value.HasFormat {
+					e.PutInt(2, int(item.Value.Format))
+				}
+			})
+		}
+		e.s.PutBytes(item.Bob)
+	})
+}
+
+func (e *Encoder) PutTextFormat(format TextFormat) {
+	// the character id is not tagged
+	e.s.WriteByte(byte(format.CharId.Author()))
+	e.s.PutVarUInt64(format.CharId.Counter())
+	e.PutCrdtId(1, format.Style.Timestamp)
+	e.PutSubBlock(2, func(e *Encoder) {
+		e.s.WriteByte(textFormatMarker)
+		e.s.WriteByte(byte(format.Style.Value))
+	})
+}
+
+func (e *Encoder) WriteMigrationInfo(migrationInfo MigrationInfo) {
+	e.PutCrdtId(1, migrationInfo.MigrationId)
+	e.PutBool(2, migrationInfo.IsDevice)
+	e.s.PutBytes(migrationInfo.Bob)
+}
+
+// WriteRootText writes the payload of the root text block
+func (e *Encoder) WriteRootText(text *SceneTextItem) {
+	e.PutCrdtId(1, text.ParentId)
+	e.PutSubBlock(2, func(e *Encoder) {
+		e.PutSubBlock(1, func(e *Encoder) {
+			e.PutSubBlock(1, func(e *Encoder) {
+				items := text.Sequence.Container
+				e.s.PutVarUInt32(uint32(len(items)))
+				for _, item := range items {
+					e.PutTextItem(item)
+				}
+				e.s.PutBytes(text.Sequence.Bob)
+			})
+		})
+		e.PutSubBlock(2, func(e *Encoder) {
+			e.PutSubBlock(1, func(e *Encoder) {
+				e.s.PutVarUInt32(uint32(len(text.Formats)))
+				for _, format := range text.Formats {
+					e.PutTextFormat(format)
+				}
+			})
+		})
+	})
+	e.PutSubBlock(3, func(e *Encoder) {
+		e.s.PutFloat64(text.Position.X)
+		e.s.PutFloat64(text.Position.Y)
+	})
+	e.PutFloat(4, text.Width)
+	e.s.PutBytes(text.Bob)
+}
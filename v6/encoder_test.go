@@ -0,0 +1,53 @@
+package v6
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// the strings of the text are encoded as the device wrote them
+func TestPutRawString(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "notebooks", "v6_text.rm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scene, err := ReadScene(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		text string
+		want []byte
+	}{
+		{"ascii", "abc", []byte{3, 1, 'a', 'b', 'c'}},
+		{"utf-8", "тест", []byte{8, 1, 0xd1, 0x82, 0xd0, 0xb5, 0xd1, 0x81, 0xd1, 0x82}},
+		{"invalid", "\xff", []byte{1, 0, 0xff}},
+	}
+	for _, test := range tests {
+		e := NewEncoder()
+		e.putRawString(test.text)
+		if !bytes.Equal(e.Bytes(), test.want) {
+			t.Errorf("%s: % x, want % x", test.name, e.Bytes(), test.want)
+		}
+	}
+
+	utf8 := false
+	for _, item := range scene.Text.Sequence.Container {
+		text := item.Value.Text
+		if text == "" {
+			continue
+		}
+		e := NewEncoder()
+		e.putRawString(text)
+		if !bytes.Contains(data, e.Bytes()) {
+			t.Errorf("%q is written as % x, not found in the file", text, e.Bytes())
+		}
+		utf8 = utf8 || bytes.ContainsRune([]byte(text), 'т')
+	}
+	if !utf8 {
+		t.Error("the notebook has no utf-8 text")
+	}
+}
@@ -0,0 +1,151 @@
+package v6
+
+import "math"
+
+// EraseResult holds the items produced by an area erase
+type EraseResult struct {
+	// Lines are the surviving pieces of the erased strokes, as new items
+	Lines []*LineItem
+	// Tombstones are the original strokes, now deleted
+	Tombstones []*LineItem
+}
+
+// EraseArea removes the points inside the shape, like the erase area tool.
+// Every stroke that is hit is split into the pieces outside the shape,
+// which replace the original in its layer.
+func (s *Scene) EraseArea(shape Shape) (result EraseResult) {
+	for _, layer := range s.Layers {
+		var lines []*LineItem
+		for _, line := range layer.Lines {
+			pieces, hit := s.SplitLine(line, shape)
+			if !hit {
+				lines = append(lines, line)
+				continue
+			}
+			lines = append(lines, pieces...)
+			result.Lines = append(result.Lines, pieces...)
+
+			line.Delete()
+			result.Tombstones = append(result.Tombstones, line)
+		}
+		layer.Lines = lines
+	}
+	return
+}
+
+// SplitLine returns the runs of the line outside the shape as new lines,
+// the line is not changed. Segments are cut where they cross the edges of
+// the shape, so a stroke that passes through the shape between two points
+// is cut too. Runs of less than two points are dropped.
+func (s *Scene) SplitLine(line *LineItem, shape Shape) (pieces []*LineItem, hit bool) {
+	points := line.Line.Value.Points
+	length := line.Line.Value.StartingLength
+	var run []*PenPoint
+	var runStart float32
+
+	flush := func() {
+		if len(run) < 2 {
+			run = nil
+			return
+		}
+		piece := &LineItem{
+			SceneItem: SceneItem{
+				Id:       s.NewId(),
+				ParentId: line.ParentId,
+				Type:     LineType,
+				Info:     line.Info,
+				IsDirty:  true,
+			},
+		}
+		piece.Line.Value = line.Line.Value
+		piece.Line.Value.Points = run
+		piece.Line.Value.StartingLength = runStart
+		piece.Line.Value.UpdateBoundingRect()
+		piece.Line.Timestamp = s.NewId()
+		pieces = append(pieces, piece)
+		run = nil
+	}
+	add := func(p *PenPoint, at float32) {
+		if len(run) == 0 {
+			runStart = at
+		}
+		run = append(run, p)
+	}
+
+	for i, p := range points {
+		if i == 0 {
+			if shape.Contains(p.X, p.Y) {
+				hit = true
+			} else {
+				point := *p
+				add(&point, length)
+			}
+			continue
+		}
+		prev := points[i-1]
+		segment := float32(math.Hypot(float64(p.X-prev.X), float64(p.Y-prev.Y)))
+		cuts := append(append([]float64{0}, crossings(shape, prev, p)...), 1)
+		for j := 1; j < len(cuts); j++ {
+			t0, t1 := cuts[j-1], cuts[j]
+			mid := interpolatePoint(prev, p, (t0+t1)/2)
+			if shape.Contains(mid.X, mid.Y) {
+				hit = true
+				flush()
+				continue
+			}
+			mt := (t0 + t1) / 2
+			if len(run) == 0 {
+				start, at := outsidePoint(shape, prev, p, t0, mt)
+				add(start, length+segment*float32(at))
+			}
+			end, at := outsidePoint(shape, prev, p, t1, mt)
+			add(end, length+segment*float32(at))
+		}
+		length += segment
+	}
+	if !hit {
+		return nil, false
+	}
+	flush()
+	return
+}
+
+// outsidePoint is the point at t between a and b, moved towards the point
+// at toward, which is outside, when it is on the edge of the shape. Cuts
+// are on the edge and the shape contains its edge.
+func outsidePoint(shape Shape, a, b *PenPoint, t, toward float64) (*PenPoint, float64) {
+	at := t
+	for step := (toward - t) / (1 << 20); ; step *= 2 {
+		point := interpolatePoint(a, b, at)
+		if !shape.Contains(point.X, point.Y) || at == toward {
+			return point, at
+		}
+		at = t + step
+		if math.Abs(step) >= math.Abs(toward-t) {
+			at = toward
+		}
+	}
+}
+
+// interpolatePoint is the point at t between a and b, a new point
+func interpolatePoint(a, b *PenPoint, t float64) *PenPoint {
+	switch t {
+	case 0:
+		p := *a
+		return &p
+	case 1:
+		p := *b
+		return &p
+	}
+	lerp := func(a, b float64) float64 {
+		return a + (b-a)*t
+	}
+	return &PenPoint{
+		X:         lerp32(a.X, b.X, t),
+		Y:         lerp32(a.Y, b.Y, t),
+		Speed:     uint16(math.Round(lerp(float64(a.Speed), float64(b.Speed)))),
+		Width:     uint16(math.Round(lerp(float64(a.Width), float64(b.Width)))),
+		Direction: a.Direction,
+		Pressure:  byte(math.Round(lerp(float64(a.Pressure), float64(b.Pressure)))),
+	}
+}
@@ -0,0 +1,40 @@
+package v6
+
+import "testing"
+
+func TestEraseArea(t *testing.T) {
+	tests := []struct {
+		name   string
+		shape  Shape
+		counts []int
+		erased int
+	}{
+		{"miss", Rect{MinX: 200, MinY: 200, MaxX: 300, MaxY: 300}, []int{11, 2}, 0},
+		{"middle", Rect{MinX: 45, MinY: -5, MaxX: 55, MaxY: 5}, []int{6, 6, 2}, 1},
+		{"between points", Rect{MinX: 41, MinY: -5, MaxX: 49, MaxY: 5}, []int{6, 7, 2}, 1},
+		{"start", Rect{MinX: -5, MinY: -5, MaxX: 25, MaxY: 5}, []int{9, 2}, 1},
+		{"single point left", Rect{MinX: 5, MinY: -5, MaxX: 100, MaxY: 5}, []int{2, 2}, 1},
+		{"both lines", Rect{MinX: 40, MinY: -5, MaxX: 60, MaxY: 55}, []int{5, 5, 2, 2}, 2},
+		{"everything", Rect{MinX: -10, MinY: -10, MaxX: 110, MaxY: 60}, nil, 2},
+		{"triangle", Polygon{{X: 50, Y: -10}, {X: 60, Y: 60}, {X: 40, Y: 60}}, []int{6, 6, 2, 2}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scene := testScene()
+			result := scene.EraseArea(test.shape)
+			if counts := pointCounts(scene.Layers[0].Lines); !equalCounts(counts, test.counts) {
+				t.Errorf("points of the lines %v, want %v", counts, test.counts)
+			}
+			if len(result.Tombstones) != test.erased {
+				t.Errorf("%d lines erased, want %d", len(result.Tombstones), test.erased)
+			}
+			for _, line := range scene.Layers[0].Lines {
+				for _, p := range line.Line.Value.Points {
+					if test.shape.Contains(p.X, p.Y) {
+						t.Errorf("point %v,%v left in the shape", p.X, p.Y)
+					}
+				}
+			}
+		})
+	}
+}
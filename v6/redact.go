@@ -0,0 +1,206 @@
+package v6
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// texts shorter than this are only checked in the parsed scene, they are
+// too likely to show up in unrelated bytes
+const minSearchLength = 4
+
+// Redaction describes what has to be removed from a page
+type Redaction struct {
+	// Area is erased: strokes are cut and highlights touching it removed
+	Area Shape
+	// Phrases are removed from the typed text and the highlights
+	Phrases []string
+}
+
+// Redacted is what was removed from a scene, used to check the written file
+type Redacted struct {
+	Redaction
+	Points []PenPoint
+	Texts  []string
+	Ids    []CrdtId
+}
+
+// Redact physically removes the content selected by the redaction. Unlike
+// a plain delete nothing of the removed content is kept in the scene:
+// erased items become tombstones without a value or Bob.
+func (s *Scene) Redact(r Redaction) (redacted *Redacted) {
+	redacted = &Redacted{Redaction: r}
+
+	if r.Area != nil {
+		for _, layer := range s.Layers {
+			for _, line := range layer.Lines {
+				for _, p := range line.Line.Value.Points {
+					if r.Area.Contains(p.X, p.Y) {
+						redacted.Points = append(redacted.Points, *p)
+					}
+				}
+			}
+		}
+		erased := s.EraseArea(r.Area)
+		for _, line := range erased.Tombstones {
+			redacted.Ids = append(redacted.Ids, line.Id)
+		}
+	}
+
+	for _, layer := range s.Layers {
+		var highlights []*GlyphRange
+		for _, highlight := range layer.Highlights {
+			if !r.hits(highlight) {
+				highlights = append(highlights, highlight)
+				continue
+			}
+			redacted.Texts = append(redacted.Texts, highlight.Text)
+			redacted.Ids = append(redacted.Ids, highlight.Id)
+			highlight.Delete()
+		}
+		layer.Highlights = highlights
+	}
+
+	if s.Text != nil && len(r.Phrases) > 0 {
+		s.redactText(r.Phrases)
+	}
+	return
+}
+
+func (r Redaction) hits(highlight *GlyphRange) bool {
+	for _, phrase := range r.Phrases {
+		if strings.Contains(highlight.Text, phrase) {
+			return true
+		}
+	}
+	if r.Area == nil {
+		return false
+	}
+	bounds := r.Area.Bounds()
+	for _, rect := range highlight.Rectangles {
+		if bounds.Intersects(Rect{
+			MinX: float32(rect.Min.X),
+			MinY: float32(rect.Min.Y),
+			MaxX: float32(rect.Max.X),
+			MaxY: float32(rect.Max.Y),
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactText deletes every occurrence of the phrases
+func (s *Scene) redactText(phrases []string) {
+	var chars []TextChar
+	for _, c := range s.Text.Chars() {
+		if !c.Deleted {
+			chars = append(chars, c)
+		}
+	}
+	runes := make([]rune, len(chars))
+	for i, c := range chars {
+		runes[i] = c.Rune
+	}
+
+	ids := make(map[CrdtId]bool)
+	for _, phrase := range phrases {
+		p := []rune(phrase)
+		if len(p) == 0 {
+			continue
+		}
+		for i := 0; i+len(p) <= len(runes); i++ {
+			if string(runes[i:i+len(p)]) != phrase {
+				continue
+			}
+			for j := i; j < i+len(p); j++ {
+				ids[chars[j].Id] = true
+			}
+		}
+	}
+	if len(ids) > 0 {
+		s.Text.DeleteChars(ids)
+	}
+}
+
+// Verify checks that the written file does not contain anything that was
+// redacted, neither in the parsed scene nor in the raw bytes
+func (r *Redacted) Verify(data []byte) (err error) {
+	scene, err := ReadScene(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("redacted file can't be read: %w", err)
+	}
+
+	removed := make(map[CrdtId]bool)
+	for _, id := range r.Ids {
+		removed[id] = true
+	}
+	for _, layer := range scene.Layers {
+		for _, line := range layer.Lines {
+			if removed[line.Id] {
+				return fmt.Errorf("redacted line %v still present", line.Id)
+			}
+			for _, p := range line.Line.Value.Points {
+				if r.Area != nil && r.Area.Contains(p.X, p.Y) {
+					return fmt.Errorf("line %v has a point in the redacted area", line.Id)
+				}
+			}
+		}
+		for _, highlight := range layer.Highlights {
+			if removed[highlight.Id] {
+				return fmt.Errorf("redacted highlight %v still present", highlight.Id)
+			}
+		}
+	}
+	if scene.Text != nil {
+		text := scene.Text.Text()
+		for _, phrase := range r.Phrases {
+			if phrase != "" && strings.Contains(text, phrase) {
+				return fmt.Errorf("redacted text %q still present", phrase)
+			}
+		}
+	}
+
+	// the raw content must not be anywhere in the file
+	for _, p := range r.Points {
+		e := NewEncoder()
+		e.s.PutFloat32(p.X)
+		e.s.PutFloat32(p.Y)
+		if bytes.Contains(data, e.Bytes()) {
+			return fmt.Errorf("redacted point %v found in file", p)
+		}
+	}
+	for _, text := range append(r.Texts, r.Phrases...) {
+		if len(text) >= minSearchLength && bytes.Contains(data, []byte(text)) {
+			return fmt.Errorf("redacted text %q found in file", text)
+		}
+	}
+	return
+}
+
+// RedactFile reads a v6 file, removes the redacted content and writes the
+// result only if the check passes
+func RedactFile(r io.Reader, w io.Writer, redaction Redaction) (err error) {
+	scene, err := ReadScene(r)
+	if err != nil {
+		return
+	}
+	redacted := scene.Redact(redaction)
+	log.Infof("redacted points: %d, items: %d", len(redacted.Points), len(redacted.Ids))
+
+	var buffer bytes.Buffer
+	err = WriteScene(&buffer, &scene)
+	if err != nil {
+		return
+	}
+	err = redacted.Verify(buffer.Bytes())
+	if err != nil {
+		return
+	}
+	_, err = w.Write(buffer.Bytes())
+	return
+}
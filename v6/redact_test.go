@@ -0,0 +1,89 @@
+package v6
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		redaction Redaction
+	}{
+		{"area", "migration_v6.rm", Redaction{Area: Rect{MinX: -200, MinY: -600, MaxX: 600, MaxY: 0}}},
+		{"phrase", "migration_v6.rm", Redaction{Phrases: []string{"test"}}},
+		{"utf-8 phrases", "v6_text.rm", Redaction{Phrases: []string{"abasd", "тест"}}},
+		{"area and phrase", "v6_text.rm", Redaction{
+			Area:    Rect{MinX: -200, MinY: -100, MaxX: 1000, MaxY: 100},
+			Phrases: []string{"abasd"},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("..", "notebooks", test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			before, err := ReadScene(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var points []PenPoint
+			if test.redaction.Area != nil {
+				for _, layer := range before.Layers {
+					for _, line := range layer.Lines {
+						for _, p := range line.Line.Value.Points {
+							if test.redaction.Area.Contains(p.X, p.Y) {
+								points = append(points, *p)
+							}
+						}
+					}
+				}
+				if len(points) == 0 {
+					t.Fatal("the area does not hit any point")
+				}
+			}
+
+			var out bytes.Buffer
+			if err := RedactFile(bytes.NewReader(data), &out, test.redaction); err != nil {
+				t.Fatal(err)
+			}
+			after, err := ReadScene(bytes.NewReader(out.Bytes()))
+			if err != nil {
+				t.Fatalf("redacted file can't be read: %v", err)
+			}
+
+			for _, layer := range after.Layers {
+				for _, line := range layer.Lines {
+					for _, p := range line.Line.Value.Points {
+						if test.redaction.Area != nil && test.redaction.Area.Contains(p.X, p.Y) {
+							t.Errorf("line %v has the point %v,%v in the area", line.Id, p.X, p.Y)
+						}
+					}
+				}
+			}
+			for _, p := range points {
+				var raw [8]byte
+				binary.LittleEndian.PutUint32(raw[:4], math.Float32bits(p.X))
+				binary.LittleEndian.PutUint32(raw[4:], math.Float32bits(p.Y))
+				if bytes.Contains(out.Bytes(), raw[:]) {
+					t.Errorf("the point %v,%v is still in the file", p.X, p.Y)
+				}
+			}
+			for _, phrase := range test.redaction.Phrases {
+				if after.Text != nil && strings.Contains(after.Text.Text(), phrase) {
+					t.Errorf("%q is still in the text", phrase)
+				}
+				if bytes.Contains(out.Bytes(), []byte(phrase)) {
+					t.Errorf("%q is still in the file", phrase)
+				}
+			}
+		})
+	}
+}
@@ -1,25 +1,38 @@
-package v6
-
-import "fmt"
-
-type Scene struct {
-	Layers        []*Layer
-	MigrationInfo MigrationInfo
-	PageInfo      PageInfo
-	UUIDMap       UUIDMap
-}
-
-func (s Scene) String() string {
-	return fmt.Sprintf("Scene: Layers: %d", len(s.Layers))
-}
-
-type Layer struct {
-	Name       string
-	Lines      []*LineItem
-	Highlights []*GlyphRange
-	IsVisible  bool
-}
-
-func (s Layer) String() string {
-	return fmt.Sprintf("Layer: name: %s lines: %d", s.Name, len(s.Lines))
-}
+package v6
+
+import "fmt"
+
+type Scene struct {
+	Layers        []*Layer
+	Text          *SceneTextItem
+	MigrationInfo MigrationInfo
+	PageInfo      PageInfo
+	UUIDMap       UUIDMap
+	NextItemId    CrdtId
+	Tree          *SceneTree
+	Blocks        []*Block
+}
+
+// Block is a top level block as it was read, kept for writing the scene back
+type Block struct {
+	Header  Header
+	Payload []byte
+	// Item is the scene item decoded from the block, if any
+	Item SceneBaseItem
+}
+
+func (s Scene) String() string {
+	return fmt.Sprintf("Scene: Layers: %d", len(s.Layers))
+}
+
+type Layer struct {
+	Id         CrdtId
+	Name       string
+	Lines      []*LineItem
+	Highlights []*GlyphRange
+	IsVisible  bool
+}
+
+func (s Layer) String() string {
+	return fmt.Sprintf("Layer: name: %s lines: %d", s.Name, len(s.Lines))
+}
@@ -1,109 +1,159 @@
-package v6
-
-import (
-	"fmt"
-	"image"
-)
-
-type SceneBaseItem interface {
-	Item() *SceneItem
-}
-
-type SceneItem struct {
-	Id       CrdtId
-	ParentId CrdtId
-	Type     SceneType
-	Info     Info
-	IsDirty  bool
-	Bob      []byte
-}
-
-func (t SceneItem) String() string {
-	return fmt.Sprintf("SceneItem (%v) Id:%v Parent:%v", t.Type, t.Id, t.ParentId)
-}
-
-func (t *SceneItem) Item() *SceneItem {
-	return t
-}
-
-type LineItem struct {
-	SceneItem
-	Line Lww[Line]
-}
-
-func (t *LineItem) Item() *SceneItem {
-	return &t.SceneItem
-}
-
-func (t LineItem) String() string {
-	return fmt.Sprintf("LineItem: Id:%v,  %v, timestamp:%d", t.Id, t.Line.Value, t.Line.Timestamp)
-}
-
-type SceneTextItem struct {
-	SceneItem
-	Sequence Sequence[*Item[TextItem]]
-	Position Point
-}
-type Point struct {
-	X float64
-	Y float64
-}
-
-func (t *SceneTextItem) Item() *SceneItem {
-	return &t.SceneItem
-}
-
-func (t SceneTextItem) String() string {
-	return fmt.Sprintf("SceneText: Id: %v, containerId:%v num:%d", t.Id, t.Sequence.Id, len(t.Sequence.Container))
-}
-
-type GlyphRange struct {
-	SceneItem
-	Start            int
-	Length           int
-	Color            byte
-	Text             string
-	Rectangles       []*image.Rectangle
-	FirstId          CrdtId
-	LastId           CrdtId
-	IsLastIdIncluded bool
-}
-
-func (t *GlyphRange) Item() *SceneItem {
-	return &t.SceneItem
-}
-
-func (t GlyphRange) String() string {
-	return fmt.Sprintf("GlyphRange: Id: %v, Text:%s Length:%d", t.Id, t.Text, t.Length)
-}
-
-type GroupItem struct {
-	SceneItem
-	NodeId   CrdtId
-	TreeNode SceneTreeNode
-}
-
-func (t *GroupItem) Item() *SceneItem {
-	return &t.SceneItem
-}
-
-func (t GroupItem) String() string {
-	return fmt.Sprintf("GroupItem: Id:%v, TreeNodeId:%d", t.NodeId, t.TreeNode.Id)
-}
-
-type TextItem struct {
-	Text   string
-	Format uint32
-}
-type Item[T any] struct {
-	Id            CrdtId
-	Left          CrdtId
-	Right         CrdtId
-	Value         T
-	DeletedLength int
-	Bob           []byte
-}
-
-func (t Item[T]) String() string {
-	return fmt.Sprintf("Item: Id: %v Val: %v", t.Id, t.Value)
-}
+package v6
+
+import (
+	"fmt"
+	"image"
+)
+
+type SceneBaseItem interface {
+	Item() *SceneItem
+}
+
+type SceneItem struct {
+	Id            CrdtId
+	ParentId      CrdtId
+	Left          CrdtId
+	Right         CrdtId
+	Type          SceneType
+	Info          Info
+	IsDirty       bool
+	DeletedLength int
+	Bob           []byte
+}
+
+func (t SceneItem) String() string {
+	return fmt.Sprintf("SceneItem (%v) Id:%v Parent:%v", t.Type, t.Id, t.ParentId)
+}
+
+func (t *SceneItem) Item() *SceneItem {
+	return t
+}
+
+// IsDeleted reports whether the item is a tombstone
+func (t *SceneItem) IsDeleted() bool {
+	return t.DeletedLength > 0
+}
+
+type LineItem struct {
+	SceneItem
+	Line Lww[Line]
+}
+
+func (t *LineItem) Item() *SceneItem {
+	return &t.SceneItem
+}
+
+// Delete turns the line into a tombstone, dropping its points
+func (t *LineItem) Delete() {
+	t.DeletedLength = 1
+	t.Line = Lww[Line]{}
+	t.Bob = nil
+	t.IsDirty = true
+}
+
+func (t LineItem) String() string {
+	return fmt.Sprintf("LineItem: Id:%v,  %v, timestamp:%d", t.Id, t.Line.Value, t.Line.Timestamp)
+}
+
+type SceneTextItem struct {
+	SceneItem
+	Sequence Sequence[*Item[TextItem]]
+	Formats  []TextFormat
+	Position Point
+	Width    float32
+}
+type Point struct {
+	X float64
+	Y float64
+}
+
+func (t *SceneTextItem) Item() *SceneItem {
+	return &t.SceneItem
+}
+
+func (t SceneTextItem) String() string {
+	return fmt.Sprintf("SceneText: Id: %v, containerId:%v num:%d", t.Id, t.Sequence.Id, len(t.Sequence.Container))
+}
+
+type GlyphRange struct {
+	SceneItem
+	Start            int
+	Length           int
+	Color            PenColor
+	Text             string
+	Rectangles       []*image.Rectangle
+	FirstId          CrdtId
+	LastId           CrdtId
+	IsLastIdIncluded bool
+}
+
+func (t *GlyphRange) Item() *SceneItem {
+	return &t.SceneItem
+}
+
+// Delete turns the highlight into a tombstone, dropping its text
+func (t *GlyphRange) Delete() {
+	t.DeletedLength = 1
+	t.Text = ""
+	t.Rectangles = nil
+	t.Bob = nil
+	t.IsDirty = true
+}
+
+func (t GlyphRange) String() string {
+	return fmt.Sprintf("GlyphRange: Id: %v, Text:%s Length:%d", t.Id, t.Text, t.Length)
+}
+
+type GroupItem struct {
+	SceneItem
+	NodeId   CrdtId
+	TreeNode SceneTreeNode
+}
+
+func (t *GroupItem) Item() *SceneItem {
+	return &t.SceneItem
+}
+
+func (t GroupItem) String() string {
+	return fmt.Sprintf("GroupItem: Id:%v, TreeNodeId:%d", t.NodeId, t.TreeNode.Id)
+}
+
+type TextItem struct {
+	Text      string
+	Format    uint32
+	HasFormat bool
+}
+
+type ParagraphStyle byte
+
+const (
+	StyleBasic           ParagraphStyle = 0
+	StylePlain           ParagraphStyle = 1
+	StyleHeading         ParagraphStyle = 2
+	StyleBold            ParagraphStyle = 3
+	StyleBullet          ParagraphStyle = 4
+	StyleBullet2         ParagraphStyle = 5
+	StyleCheckbox        ParagraphStyle = 6
+	StyleCheckboxChecked ParagraphStyle = 7
+)
+
+// every text format starts with this byte
+const textFormatMarker = 17
+
+// TextFormat is the paragraph style starting at a character
+type TextFormat struct {
+	CharId CrdtId
+	Style  Lww[ParagraphStyle]
+}
+type Item[T any] struct {
+	Id            CrdtId
+	Left          CrdtId
+	Right         CrdtId
+	Value         T
+	DeletedLength int
+	Bob           []byte
+}
+
+func (t Item[T]) String() string {
+	return fmt.Sprintf("Item: Id: %v Val: %v", t.Id, t.Value)
+}
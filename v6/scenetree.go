@@ -1,102 +1,164 @@
-package v6
-
-import (
-	"github.com/sirupsen/logrus"
-)
-
-type SceneTree struct {
-	NextItemId CrdtId
-	tree       Tree[Info]
-
-	NodeMap map[CrdtId]*Node
-	Root    *Node
-	Layers  []*Layer
-}
-type Node struct {
-	Id       CrdtId
-	Children []*Node
-	IsLayer  bool
-	Layer    int
-	Value    *SceneTreeNode
-}
-
-func (n *Node) Add(c *Node) {
-	n.Children = append(n.Children, n)
-}
-
-type Tree[T any] struct {
-	Id      CrdtId
-	NodeMap map[CrdtId]T
-}
-
-const rootId = CrdtId(1)
-
-func NewTree() (tree *SceneTree) {
-
-	rootNode := &Node{
-		Id: rootId,
-	}
-	return &SceneTree{
-		NodeMap: map[CrdtId]*Node{rootId: rootNode},
-		tree: Tree[Info]{
-			NodeMap: make(map[CrdtId]Info),
-		},
-		Root: rootNode,
-	}
-}
-
-func NewNode(s *SceneTreeNode) *Node {
-	return &Node{
-		Id: s.Id,
-	}
-}
-func NewNodeM(s *TreeMoveInfo) *Node {
-	return &Node{
-		Id: s.Id,
-	}
-}
-func (t *SceneTree) AddTree(mi *TreeMoveInfo) {
-	n := NewNodeM(mi)
-	t.NodeMap[mi.Id] = n
-	parentId := mi.ItemInfo.ParentId
-	if parentId == rootId {
-		n.IsLayer = true
-	}
-	parent, ok := t.NodeMap[parentId]
-	if !ok {
-		logrus.Warn("Parent not found! ", parentId)
-	} else {
-		parent.Add(n)
-
-	}
-}
-func (t *SceneTree) AddNode(mi *SceneTreeNode) {
-	node, ok := t.NodeMap[mi.Id]
-	if ok && node.IsLayer {
-		l := &Layer{
-			Name:      mi.Name.Value,
-			IsVisible: mi.Visible.Value,
-		}
-		node.Layer = len(t.Layers)
-		t.Layers = append(t.Layers, l)
-		return
-	}
-	if !ok {
-		logrus.Warn("Node not found ", mi.Id)
-	}
-}
-func (t *SceneTree) AddItem(item Item[SceneBaseItem], parent CrdtId) {
-	node, ok := t.NodeMap[parent]
-	if !ok {
-		logrus.Warn("cannot find layer", parent)
-		return
-	}
-	switch v := item.Value.(type) {
-	case *LineItem:
-		layer := t.Layers[node.Layer]
-		layer.Lines = append(layer.Lines, v)
-		logrus.Info("Got LineItem: ", v.Id)
-	}
-}
-func (t *SceneTree) AddRootText(mi *SceneTextItem) {
-}
+package v6
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+type SceneTree struct {
+	NextItemId CrdtId
+	tree       Tree[Info]
+
+	NodeMap map[CrdtId]*Node
+	Root    *Node
+	Layers  []*Layer
+	Text    *SceneTextItem
+}
+type Node struct {
+	Id       CrdtId
+	Parent   *Node
+	Children []*Node
+	IsLayer  bool
+	Layer    int
+	Value    *SceneTreeNode
+}
+
+func (n *Node) Add(c *Node) {
+	c.Parent = n
+	n.Children = append(n.Children, c)
+}
+
+// LayerNode returns the layer the node belongs to, nil for the root
+func (n *Node) LayerNode() *Node {
+	for ; n != nil; n = n.Parent {
+		if n.IsLayer {
+			return n
+		}
+	}
+	return nil
+}
+
+// IsDescendant reports whether the node is the ancestor or below it
+func (n *Node) IsDescendant(ancestor CrdtId) bool {
+	for ; n != nil; n = n.Parent {
+		if n.Id == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+type Tree[T any] struct {
+	Id      CrdtId
+	NodeMap map[CrdtId]T
+}
+
+const rootId = CrdtId(1)
+
+func NewTree() (tree *SceneTree) {
+
+	rootNode := &Node{
+		Id: rootId,
+	}
+	return &SceneTree{
+		NodeMap: map[CrdtId]*Node{rootId: rootNode},
+		tree: Tree[Info]{
+			NodeMap: make(map[CrdtId]Info),
+		},
+		Root: rootNode,
+	}
+}
+
+func NewNode(s *SceneTreeNode) *Node {
+	return &Node{
+		Id: s.Id,
+	}
+}
+func NewNodeM(s *TreeMoveInfo) *Node {
+	return &Node{
+		Id: s.Id,
+	}
+}
+
+// seen keeps NextItemId past every id found in the file
+func (t *SceneTree) seen(id CrdtId) {
+	if id.Counter() >= t.NextItemId.Counter() {
+		t.NextItemId = NewCrdtId(LocalAuthor, id.Counter()+1)
+	}
+}
+
+func (t *SceneTree) AddTree(mi *TreeMoveInfo) {
+	t.seen(mi.Id)
+	n := NewNodeM(mi)
+	t.NodeMap[mi.Id] = n
+	parentId := mi.ItemInfo.ParentId
+	if parentId == rootId {
+		n.IsLayer = true
+	}
+	parent, ok := t.NodeMap[parentId]
+	if !ok {
+		logrus.Warn("Parent not found! ", parentId)
+	} else {
+		parent.Add(n)
+
+	}
+}
+func (t *SceneTree) AddNode(mi *SceneTreeNode) {
+	node, ok := t.NodeMap[mi.Id]
+	if ok {
+		node.Value = mi
+	}
+	if ok && node.IsLayer {
+		l := &Layer{
+			Id:        mi.Id,
+			Name:      mi.Name.Value,
+			IsVisible: mi.Visible.Value,
+		}
+		node.Layer = len(t.Layers)
+		t.Layers = append(t.Layers, l)
+		return
+	}
+	if !ok {
+		logrus.Warn("Node not found ", mi.Id)
+	}
+}
+func (t *SceneTree) AddItem(item Item[SceneBaseItem], parent CrdtId) {
+	t.seen(item.Id)
+	node, ok := t.NodeMap[parent]
+	if !ok {
+		logrus.Warn("cannot find layer", parent)
+		return
+	}
+	if item.Value == nil {
+		// tombstone
+		return
+	}
+	sceneItem := item.Value.Item()
+	sceneItem.Id = item.Id
+	sceneItem.ParentId = parent
+	sceneItem.Left = item.Left
+	sceneItem.Right = item.Right
+	sceneItem.DeletedLength = item.DeletedLength
+
+	layerNode := node.LayerNode()
+	if layerNode == nil || layerNode.Layer >= len(t.Layers) {
+		logrus.Warn("item outside of a layer ", item.Id)
+		return
+	}
+	layer := t.Layers[layerNode.Layer]
+
+	switch v := item.Value.(type) {
+	case *LineItem:
+		t.seen(v.Line.Timestamp)
+		layer.Lines = append(layer.Lines, v)
+		logrus.Info("Got LineItem: ", v.Id)
+	case *GlyphRange:
+		layer.Highlights = append(layer.Highlights, v)
+		logrus.Info("Got GlyphRange: ", v.Id)
+	}
+}
+func (t *SceneTree) AddRootText(mi *SceneTextItem) {
+	for _, item := range mi.Sequence.Container {
+		t.seen(item.Id)
+	}
+	t.Text = mi
+}
@@ -0,0 +1,180 @@
+package v6
+
+import (
+	"math"
+	"sort"
+)
+
+// Shape is an area in page coordinates
+type Shape interface {
+	Contains(x, y float32) bool
+	Bounds() Rect
+}
+
+// Rect is an axis aligned rectangle in page coordinates
+type Rect struct {
+	MinX float32
+	MinY float32
+	MaxX float32
+	MaxY float32
+}
+
+// EmptyRect contains nothing, the starting point for Union
+var EmptyRect = Rect{MinX: 1, MinY: 1}
+
+func NewRect(x, y, width, height float32) Rect {
+	return Rect{
+		MinX: x,
+		MinY: y,
+		MaxX: x + width,
+		MaxY: y + height,
+	}
+}
+
+func (r Rect) Contains(x, y float32) bool {
+	return x >= r.MinX && x <= r.MaxX && y >= r.MinY && y <= r.MaxY
+}
+
+func (r Rect) Bounds() Rect {
+	return r
+}
+
+func (r Rect) Width() float32 {
+	return r.MaxX - r.MinX
+}
+
+func (r Rect) Height() float32 {
+	return r.MaxY - r.MinY
+}
+
+func (r Rect) Empty() bool {
+	return r.MaxX < r.MinX || r.MaxY < r.MinY
+}
+
+// Union returns the smallest rectangle containing both rectangles
+func (r Rect) Union(o Rect) Rect {
+	if r.Empty() {
+		return o
+	}
+	if o.Empty() {
+		return r
+	}
+	return Rect{
+		MinX: float32(math.Min(float64(r.MinX), float64(o.MinX))),
+		MinY: float32(math.Min(float64(r.MinY), float64(o.MinY))),
+		MaxX: float32(math.Max(float64(r.MaxX), float64(o.MaxX))),
+		MaxY: float32(math.Max(float64(r.MaxY), float64(o.MaxY))),
+	}
+}
+
+// Intersects reports whether the rectangles overlap
+func (r Rect) Intersects(o Rect) bool {
+	return !r.Empty() && !o.Empty() &&
+		r.MinX <= o.MaxX && o.MinX <= r.MaxX &&
+		r.MinY <= o.MaxY && o.MinY <= r.MaxY
+}
+
+// Polygon is a closed polygon in page coordinates, e.g. a lasso selection
+type Polygon []Point
+
+func (p Polygon) Contains(x, y float32) bool {
+	// even-odd ray casting
+	inside := false
+	px, py := float64(x), float64(y)
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		a, b := p[i], p[j]
+		if (a.Y > py) != (b.Y > py) &&
+			px < (b.X-a.X)*(py-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func (p Polygon) Bounds() Rect {
+	r := EmptyRect
+	for _, pt := range p {
+		x, y := float32(pt.X), float32(pt.Y)
+		r = r.Union(Rect{MinX: x, MinY: y, MaxX: x, MaxY: y})
+	}
+	return r
+}
+
+// crosser is implemented by shapes with straight edges
+type crosser interface {
+	// Crossings returns where the segment from a to b crosses the edges of
+	// the shape, as fractions of the segment in increasing order
+	Crossings(ax, ay, bx, by float32) []float64
+}
+
+func (r Rect) Crossings(ax, ay, bx, by float32) []float64 {
+	return Polygon{
+		{X: float64(r.MinX), Y: float64(r.MinY)},
+		{X: float64(r.MaxX), Y: float64(r.MinY)},
+		{X: float64(r.MaxX), Y: float64(r.MaxY)},
+		{X: float64(r.MinX), Y: float64(r.MaxY)},
+	}.Crossings(ax, ay, bx, by)
+}
+
+func (p Polygon) Crossings(ax, ay, bx, by float32) (ts []float64) {
+	x0, y0 := float64(ax), float64(ay)
+	dx, dy := float64(bx)-x0, float64(by)-y0
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		ex, ey := p[i].X-p[j].X, p[i].Y-p[j].Y
+		denom := dx*ey - dy*ex
+		if denom == 0 {
+			continue
+		}
+		// t along the segment, u along the edge
+		t := ((p[j].X-x0)*ey - (p[j].Y-y0)*ex) / denom
+		u := ((p[j].X-x0)*dy - (p[j].Y-y0)*dx) / denom
+		if t > 0 && t < 1 && u >= 0 && u <= 1 {
+			ts = append(ts, t)
+		}
+	}
+	sort.Float64s(ts)
+	return
+}
+
+// crossings of the segment with the shape, shapes without straight edges
+// are sampled along the segment and the changes are bisected
+func crossings(shape Shape, a, b *PenPoint) []float64 {
+	if c, ok := shape.(crosser); ok {
+		return c.Crossings(a.X, a.Y, b.X, b.Y)
+	}
+	inside := func(t float64) bool {
+		x, y := lerp32(a.X, b.X, t), lerp32(a.Y, b.Y, t)
+		return shape.Contains(x, y)
+	}
+	length := math.Hypot(float64(b.X-a.X), float64(b.Y-a.Y))
+	steps := int(math.Min(math.Ceil(length/crossingStep), maxCrossingSteps))
+	var ts []float64
+	prev := inside(0)
+	for i := 1; i <= steps; i++ {
+		lo, hi := float64(i-1)/float64(steps), float64(i)/float64(steps)
+		if inside(hi) == prev {
+			continue
+		}
+		for n := 0; n < 16; n++ {
+			mid := (lo + hi) / 2
+			if inside(mid) == prev {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		ts = append(ts, (lo+hi)/2)
+		prev = !prev
+	}
+	return ts
+}
+
+// sampling of shapes without straight edges, in pixels
+const (
+	crossingStep     = 2
+	maxCrossingSteps = 256
+)
+
+func lerp32(a, b float32, t float64) float32 {
+	return a + float32(float64(b-a)*t)
+}
@@ -0,0 +1,165 @@
+package v6
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// TextChar is a single character of the root text
+type TextChar struct {
+	Id      CrdtId
+	Left    CrdtId
+	Right   CrdtId
+	Rune    rune
+	Deleted bool
+}
+
+// expandChars splits the text items into single characters, every
+// character of an item has its own id
+func expandChars(items []*Item[TextItem]) (chars []TextChar) {
+	for _, item := range items {
+		var runes []rune
+		deleted := item.DeletedLength > 0
+		if deleted {
+			runes = make([]rune, item.DeletedLength)
+		} else {
+			runes = []rune(item.Value.Text)
+		}
+		for i, r := range runes {
+			id := item.Id + CrdtId(i)
+			c := TextChar{
+				Id:      id,
+				Left:    id - 1,
+				Right:   id + 1,
+				Rune:    r,
+				Deleted: deleted,
+			}
+			if i == 0 {
+				c.Left = item.Left
+			}
+			if i == len(runes)-1 {
+				c.Right = item.Right
+			}
+			chars = append(chars, c)
+		}
+	}
+	return
+}
+
+// sortChars orders the characters by their left and right neighbours,
+// characters that could go in the same place are ordered by id
+func sortChars(chars []TextChar) (sorted []TextChar) {
+	byId := make(map[CrdtId]int, len(chars))
+	for i, c := range chars {
+		byId[c.Id] = i
+	}
+	// number of characters that have to come first
+	pending := make([]int, len(chars))
+	next := make(map[CrdtId][]int)
+	for i, c := range chars {
+		if j, ok := byId[c.Left]; ok && c.Left != 0 {
+			pending[i]++
+			next[chars[j].Id] = append(next[chars[j].Id], i)
+		}
+		if j, ok := byId[c.Right]; ok && c.Right != 0 {
+			pending[j]++
+			next[c.Id] = append(next[c.Id], j)
+		}
+	}
+
+	var ready []int
+	for i := range chars {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(a, b int) bool {
+			return chars[ready[a]].Id < chars[ready[b]].Id
+		})
+		var following []int
+		for _, i := range ready {
+			sorted = append(sorted, chars[i])
+			for _, j := range next[chars[i].Id] {
+				pending[j]--
+				if pending[j] == 0 {
+					following = append(following, j)
+				}
+			}
+		}
+		ready = following
+	}
+	return
+}
+
+// Chars returns the characters of the text in document order, deleted
+// characters included
+func (t *SceneTextItem) Chars() []TextChar {
+	return sortChars(expandChars(t.Sequence.Container))
+}
+
+// Text returns the visible text
+func (t *SceneTextItem) Text() string {
+	buffer := make([]byte, 0, utf8.UTFMax*len(t.Sequence.Container))
+	for _, c := range t.Chars() {
+		if !c.Deleted {
+			buffer = utf8.AppendRune(buffer, c.Rune)
+		}
+	}
+	return string(buffer)
+}
+
+// DeleteChars turns the characters into tombstones, the text of the deleted
+// characters is removed. Items are split where needed.
+func (t *SceneTextItem) DeleteChars(ids map[CrdtId]bool) {
+	var items []*Item[TextItem]
+	for _, item := range t.Sequence.Container {
+		if item.DeletedLength > 0 {
+			items = append(items, item)
+			continue
+		}
+		runes := []rune(item.Value.Text)
+		hit := false
+		for i := range runes {
+			if ids[item.Id+CrdtId(i)] {
+				hit = true
+				break
+			}
+		}
+		if !hit {
+			items = append(items, item)
+			continue
+		}
+
+		// split into runs of kept and deleted characters
+		var pieces []*Item[TextItem]
+		start := 0
+		for i := 1; i <= len(runes); i++ {
+			if i < len(runes) && ids[item.Id+CrdtId(i)] == ids[item.Id+CrdtId(start)] {
+				continue
+			}
+			piece := &Item[TextItem]{
+				Id:    item.Id + CrdtId(start),
+				Left:  item.Id + CrdtId(start) - 1,
+				Right: item.Id + CrdtId(i),
+			}
+			if start == 0 {
+				piece.Left = item.Left
+			}
+			if i == len(runes) {
+				piece.Right = item.Right
+			}
+			if ids[piece.Id] {
+				piece.DeletedLength = i - start
+			} else {
+				piece.Value = item.Value
+				piece.Value.Text = string(runes[start:i])
+			}
+			pieces = append(pieces, piece)
+			start = i
+		}
+		items = append(items, pieces...)
+	}
+	t.Sequence.Container = items
+	t.IsDirty = true
+}
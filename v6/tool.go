@@ -0,0 +1,94 @@
+package v6
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tool the pen a line was drawn with
+type Tool byte
+
+// tools of the first and the second generation of pens, the second
+// generation is drawn the same way
+const (
+	ToolPaintbrush        Tool = 0
+	ToolPencil            Tool = 1
+	ToolBallpoint         Tool = 2
+	ToolMarker            Tool = 3
+	ToolFineliner         Tool = 4
+	ToolHighlighter       Tool = 5
+	ToolEraser            Tool = 6
+	ToolMechanicalPencil  Tool = 7
+	ToolEraseArea         Tool = 8
+	ToolPaintbrush2       Tool = 12
+	ToolMechanicalPencil2 Tool = 13
+	ToolPencil2           Tool = 14
+	ToolBallpoint2        Tool = 15
+	ToolMarker2           Tool = 16
+	ToolFineliner2        Tool = 17
+	ToolHighlighter2      Tool = 18
+	ToolCalligraphy       Tool = 21
+	ToolShader            Tool = 23
+)
+
+var toolNames = map[Tool]string{
+	ToolPaintbrush:       "paintbrush",
+	ToolPencil:           "pencil",
+	ToolBallpoint:        "ballpoint",
+	ToolMarker:           "marker",
+	ToolFineliner:        "fineliner",
+	ToolHighlighter:      "highlighter",
+	ToolEraser:           "eraser",
+	ToolMechanicalPencil: "mechanical pencil",
+	ToolEraseArea:        "erase area",
+	ToolCalligraphy:      "calligraphy",
+	ToolShader:           "shader",
+}
+
+// Base returns the first generation tool of a second generation tool
+func (t Tool) Base() Tool {
+	switch t {
+	case ToolPaintbrush2:
+		return ToolPaintbrush
+	case ToolMechanicalPencil2:
+		return ToolMechanicalPencil
+	case ToolPencil2:
+		return ToolPencil
+	case ToolBallpoint2:
+		return ToolBallpoint
+	case ToolMarker2:
+		return ToolMarker
+	case ToolFineliner2:
+		return ToolFineliner
+	case ToolHighlighter2:
+		return ToolHighlighter
+	}
+	return t
+}
+
+// IsEraser reports whether the line removes ink instead of adding it
+func (t Tool) IsEraser() bool {
+	return t == ToolEraser || t == ToolEraseArea
+}
+
+// IsHighlighter reports whether the line is drawn see through
+func (t Tool) IsHighlighter() bool {
+	return t.Base() == ToolHighlighter
+}
+
+func (t Tool) String() string {
+	if name, ok := toolNames[t.Base()]; ok {
+		return name
+	}
+	return fmt.Sprintf("tool %d", byte(t))
+}
+
+// ParseTool returns the first generation tool with the name, case is ignored
+func ParseTool(name string) (Tool, bool) {
+	for t, n := range toolNames {
+		if strings.EqualFold(n, name) {
+			return t, true
+		}
+	}
+	return 0, false
+}
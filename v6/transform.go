@@ -0,0 +1,142 @@
+package v6
+
+import "math"
+
+// Transform is an affine transform in page coordinates:
+//
+//	x' = A*x + C*y + E
+//	y' = B*x + D*y + F
+type Transform struct {
+	A, B, C, D, E, F float64
+}
+
+// Identity does not change anything
+var Identity = Transform{A: 1, D: 1}
+
+func Translate(dx, dy float64) Transform {
+	return Transform{A: 1, D: 1, E: dx, F: dy}
+}
+
+func Scale(sx, sy float64) Transform {
+	return Transform{A: sx, D: sy}
+}
+
+// Rotate rotates by angle radians around the origin
+func Rotate(angle float64) Transform {
+	sin, cos := math.Sincos(angle)
+	return Transform{A: cos, B: sin, C: -sin, D: cos}
+}
+
+// MirrorX mirrors left to right at the vertical line x
+func MirrorX(x float64) Transform {
+	return Transform{A: -1, D: 1, E: 2 * x}
+}
+
+// MirrorY mirrors top to bottom at the horizontal line y
+func MirrorY(y float64) Transform {
+	return Transform{A: 1, D: -1, F: 2 * y}
+}
+
+// Around applies the transform with (x, y) as the origin
+func (t Transform) Around(x, y float64) Transform {
+	return Translate(-x, -y).Then(t).Then(Translate(x, y))
+}
+
+// Then returns the transform that applies t first and then o
+func (t Transform) Then(o Transform) Transform {
+	return Transform{
+		A: o.A*t.A + o.C*t.B,
+		B: o.B*t.A + o.D*t.B,
+		C: o.A*t.C + o.C*t.D,
+		D: o.B*t.C + o.D*t.D,
+		E: o.A*t.E + o.C*t.F + o.E,
+		F: o.B*t.E + o.D*t.F + o.F,
+	}
+}
+
+func (t Transform) Apply(x, y float64) (float64, float64) {
+	return t.A*x + t.C*y + t.E, t.B*x + t.D*y + t.F
+}
+
+// ScaleFactor is how much lengths change on average, used for the widths
+func (t Transform) ScaleFactor() float64 {
+	return math.Sqrt(math.Abs(t.A*t.D - t.B*t.C))
+}
+
+// direction transforms a point direction, a full turn is 255
+func (t Transform) direction(d byte) byte {
+	angle := float64(d) * math.Pi * 2 / 255
+	sin, cos := math.Sincos(angle)
+	x, y := t.A*cos+t.C*sin, t.B*cos+t.D*sin
+	angle = math.Atan2(y, x)
+	if angle < 0 {
+		angle += math.Pi * 2
+	}
+	return byte(math.Round(255 * angle / (math.Pi * 2)))
+}
+
+// TransformLines applies the transform to the points, widths are scaled
+// and the bounding rectangles updated
+func (t Transform) TransformLines(lines []*LineItem) {
+	factor := t.ScaleFactor()
+	for _, item := range lines {
+		line := &item.Line.Value
+		// the width in the points is the width of the line, older lines
+		// have none and use ThicknessScale, only the one in use is scaled
+		widths := false
+		for _, p := range line.Points {
+			x, y := t.Apply(float64(p.X), float64(p.Y))
+			p.X = float32(x)
+			p.Y = float32(y)
+			if p.Width != 0 {
+				width := math.Round(float64(p.Width) * factor)
+				p.Width = uint16(math.Min(width, math.MaxUint16))
+				widths = true
+			}
+			p.Direction = t.direction(p.Direction)
+		}
+		if !widths {
+			line.ThicknessScale *= factor
+		}
+		line.UpdateBoundingRect()
+		item.IsDirty = true
+	}
+}
+
+// TransformLayer transforms every line of the layer
+func (s *Scene) TransformLayer(layer int, t Transform) error {
+	l, err := s.layer(layer)
+	if err != nil {
+		return err
+	}
+	t.TransformLines(l.Lines)
+	return nil
+}
+
+// GroupLines returns the lines of the group, nested groups included
+func (s *Scene) GroupLines(groupId CrdtId) (lines []*LineItem) {
+	for _, layer := range s.Layers {
+		for _, line := range layer.Lines {
+			if s.isInGroup(line.ParentId, groupId) {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return
+}
+
+func (s *Scene) isInGroup(parentId, groupId CrdtId) bool {
+	if parentId == groupId {
+		return true
+	}
+	if s.Tree == nil {
+		return false
+	}
+	node, ok := s.Tree.NodeMap[parentId]
+	return ok && node.IsDescendant(groupId)
+}
+
+// TransformGroup transforms every line in the group
+func (s *Scene) TransformGroup(groupId CrdtId, t Transform) {
+	t.TransformLines(s.GroupLines(groupId))
+}
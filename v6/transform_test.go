@@ -0,0 +1,125 @@
+package v6
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestTransformThen(t *testing.T) {
+	tests := []struct {
+		name         string
+		transform    Transform
+		x, y, wx, wy float64
+	}{
+		{"identity", Identity, 3, 4, 3, 4},
+		{"translate then scale", Translate(1, 2).Then(Scale(2, 3)), 1, 1, 4, 9},
+		{"scale then translate", Scale(2, 3).Then(Translate(1, 2)), 1, 1, 3, 5},
+		{"rotate", Rotate(math.Pi / 2), 1, 0, 0, 1},
+		{"rotate around", Rotate(math.Pi).Around(10, 10), 0, 0, 20, 20},
+		{"mirror x", MirrorX(5), 1, 1, 9, 1},
+		{"mirror y", MirrorY(5), 1, 1, 1, 9},
+	}
+	for _, test := range tests {
+		if x, y := test.transform.Apply(test.x, test.y); !near(x, test.wx) || !near(y, test.wy) {
+			t.Errorf("%s: %v,%v, want %v,%v", test.name, x, y, test.wx, test.wy)
+		}
+	}
+
+	// Then applies the transforms one after the other
+	a, b := Rotate(0.3).Then(Translate(5, -2)), Scale(1.5, 0.5).Around(3, 7)
+	x, y := a.Apply(2, 9)
+	x, y = b.Apply(x, y)
+	if cx, cy := a.Then(b).Apply(2, 9); !near(x, cx) || !near(y, cy) {
+		t.Errorf("composed %v,%v, want %v,%v", cx, cy, x, y)
+	}
+}
+
+func TestTransformLines(t *testing.T) {
+	scene := testScene()
+	lines := scene.Layers[0].Lines
+	for _, line := range lines {
+		line.Line.Value.ThicknessScale = 2
+	}
+	Rotate(math.Pi / 2).Then(Scale(2, 2)).TransformLines(lines)
+
+	withWidth, withoutWidth := lines[0].Line.Value, lines[1].Line.Value
+	for _, p := range withWidth.Points {
+		if p.Width != 16 {
+			t.Errorf("width %d, want 16", p.Width)
+		}
+		// a quarter turn of the device
+		if p.Direction != 64 {
+			t.Errorf("direction %d, want 64", p.Direction)
+		}
+	}
+	if withWidth.ThicknessScale != 2 {
+		t.Errorf("thickness %v scaled with the widths of the points, want 2", withWidth.ThicknessScale)
+	}
+	if withoutWidth.ThicknessScale != 4 {
+		t.Errorf("thickness %v of the line without widths, want 4", withoutWidth.ThicknessScale)
+	}
+	if p := withWidth.Points[10]; !near(float64(p.X), 0) || !near(float64(p.Y), 200) {
+		t.Errorf("last point %v,%v, want 0,200", p.X, p.Y)
+	}
+	if r := withWidth.BoundingRect; r.Max.Y < 199 {
+		t.Errorf("bounding rect %v not updated", r)
+	}
+	for _, line := range lines {
+		if !line.IsDirty {
+			t.Error("transformed line not dirty")
+		}
+	}
+}
+
+func TestTransformLayer(t *testing.T) {
+	scene := testScene()
+	for _, layer := range []int{-1, 1} {
+		if err := scene.TransformLayer(layer, Translate(1, 1)); !errors.Is(err, ErrNoLayer) {
+			t.Errorf("layer %d: error %v, want %v", layer, err, ErrNoLayer)
+		}
+	}
+	if err := scene.TransformLayer(0, Translate(1, 1)); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range scene.Layers[0].Lines {
+		if p := line.Line.Value.Points[0]; p.X != 1 {
+			t.Errorf("point %v,%v not moved", p.X, p.Y)
+		}
+	}
+}
+
+func TestTransformGroup(t *testing.T) {
+	scene := testScene()
+	layer := scene.Layers[0]
+	group, nested, other := NewCrdtId(0, 20), NewCrdtId(0, 21), NewCrdtId(0, 22)
+	scene.Tree = NewTree()
+	for _, node := range []struct{ id, parent CrdtId }{
+		{layer.Id, rootId}, {group, layer.Id}, {nested, group}, {other, layer.Id},
+	} {
+		scene.Tree.AddTree(&TreeMoveInfo{Id: node.id, ItemInfo: TreeItemInfo{ParentId: node.parent}})
+	}
+	point := func() *PenPoint { return &PenPoint{X: 1, Y: 1} }
+	lines := []*LineItem{
+		testLine(scene, layer, point()),
+		testLine(scene, layer, point()),
+		testLine(scene, layer, point()),
+	}
+	lines[0].ParentId, lines[1].ParentId, lines[2].ParentId = group, nested, other
+	layer.Lines = append(layer.Lines, lines...)
+
+	scene.TransformGroup(group, Translate(10, 0))
+	for i, line := range layer.Lines {
+		moved := line.ParentId == group || line.ParentId == nested
+		if p := line.Line.Value.Points[0]; (p.X >= 10) != moved {
+			t.Errorf("line %d in %v: first point at %v, moved %t", i, line.ParentId, p.X, moved)
+		}
+		if line.IsDirty != moved {
+			t.Errorf("line %d in %v: dirty %t, want %t", i, line.ParentId, line.IsDirty, moved)
+		}
+	}
+}
@@ -1,39 +1,39 @@
-package v6
-
-import (
-	"github.com/google/uuid"
-	"github.com/sirupsen/logrus"
-)
-
-type UUIDMap struct {
-	UUID2Index map[uuid.UUID]AuthorId
-	Index2UUID map[AuthorId]uuid.UUID
-	Max        AuthorId
-}
-
-func NewMap() UUIDMap {
-	return UUIDMap{
-		UUID2Index: make(map[uuid.UUID]AuthorId),
-		Index2UUID: make(map[AuthorId]uuid.UUID),
-	}
-}
-
-func (um *UUIDMap) Entries() int {
-	return len(um.Index2UUID)
-}
-
-func (um *UUIDMap) Add(u uuid.UUID, index AuthorId) {
-	logrus.Tracef("Add Author: %d", index)
-	if um.Index2UUID == nil {
-		um.Index2UUID = make(map[AuthorId]uuid.UUID)
-	}
-	if um.UUID2Index == nil {
-		um.UUID2Index = make(map[uuid.UUID]AuthorId)
-	}
-	um.Index2UUID[index] = u
-	um.UUID2Index[u] = index
-
-	if index > um.Max {
-		um.Max = index
-	}
-}
+package v6
+
+import (
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type UUIDMap struct {
+	UUID2Index map[uuid.UUID]AuthorId
+	Index2UUID map[AuthorId]uuid.UUID
+	Max        AuthorId
+}
+
+func NewMap() UUIDMap {
+	return UUIDMap{
+		UUID2Index: make(map[uuid.UUID]AuthorId),
+		Index2UUID: make(map[AuthorId]uuid.UUID),
+	}
+}
+
+func (um *UUIDMap) Entries() int {
+	return len(um.Index2UUID)
+}
+
+func (um *UUIDMap) Add(u uuid.UUID, index AuthorId) {
+	logrus.Tracef("Add Author: %d", index)
+	if um.Index2UUID == nil {
+		um.Index2UUID = make(map[AuthorId]uuid.UUID)
+	}
+	if um.UUID2Index == nil {
+		um.UUID2Index = make(map[uuid.UUID]AuthorId)
+	}
+	um.Index2UUID[index] = u
+	um.UUID2Index[u] = index
+
+	if index > um.Max {
+		um.Max = index
+	}
+}
@@ -0,0 +1,121 @@
+package v6
+
+import (
+	"io"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SceneWriter writes a scene back, blocks that were not changed are copied
+// as they were read
+type SceneWriter struct {
+	w     io.Writer
+	scene *Scene
+}
+
+// WriteScene writes a complete v6 file, header included
+func WriteScene(w io.Writer, scene *Scene) (err error) {
+	_, err = io.WriteString(w, HeaderV6)
+	if err != nil {
+		return
+	}
+	sceneWriter := SceneWriter{}
+	return sceneWriter.EncodeScene(w, scene)
+}
+
+func (s *SceneWriter) EncodeScene(w io.Writer, scene *Scene) (err error) {
+	s.w = w
+	s.scene = scene
+
+	written := make(map[SceneBaseItem]bool)
+	for _, block := range scene.Blocks {
+		header := block.Header
+		payload := block.Payload
+		if block.Item != nil {
+			written[block.Item] = true
+			if block.Item.Item().IsDirty {
+				payload = s.encodeItem(&header, block.Item, payload)
+			}
+		}
+		err = s.writeBlock(header, payload)
+		if err != nil {
+			return
+		}
+	}
+
+	// items that did not come from the file
+	for _, layer := range scene.Layers {
+		for i, line := range layer.Lines {
+			if written[line] {
+				continue
+			}
+			line.Left, line.Right = siblings(layer.Lines, i)
+			header := Header{
+				Info: HeaderInfo{
+					PayloadType: LineItemTag,
+					NodeInfo:    line.Info,
+				},
+			}
+			payload := s.encodeItem(&header, line, nil)
+			err = s.writeBlock(header, payload)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// siblings returns the ids of the lines before and after the line at i in
+// the same group, every group is a sequence of its own. The ids are zero
+// at the ends of the sequence.
+func siblings(lines []*LineItem, i int) (left, right CrdtId) {
+	parent := lines[i].ParentId
+	for j := i - 1; j >= 0; j-- {
+		if lines[j].ParentId == parent {
+			left = lines[j].Id
+			break
+		}
+	}
+	for j := i + 1; j < len(lines); j++ {
+		if lines[j].ParentId == parent {
+			right = lines[j].Id
+			break
+		}
+	}
+	return
+}
+
+func (s *SceneWriter) encodeItem(header *Header, sceneItem SceneBaseItem, payload []byte) []byte {
+	e := NewEncoder()
+	switch v := sceneItem.(type) {
+	case *LineItem:
+		// new items are written with the current point version
+		info := &header.Info.NodeInfo
+		if info.CurrentVersion == 0 {
+			info.CurrentVersion = PointVersion2
+			info.MinVersion = PointVersion2
+			v.Info = *info
+		}
+		e.WriteSceneItem(v)
+	case *SceneTextItem:
+		e.WriteRootText(v)
+	default:
+		if !sceneItem.Item().IsDeleted() {
+			log.Warnf("cannot encode %v, keeping it unchanged", sceneItem.Item())
+			return payload
+		}
+		e.WriteSceneItem(sceneItem)
+	}
+	return e.Bytes()
+}
+
+func (s *SceneWriter) writeBlock(header Header, payload []byte) (err error) {
+	header.Size = int32(len(payload))
+	err = WriteHeader(s.w, header)
+	if err != nil {
+		return
+	}
+	_, err = s.w.Write(payload)
+	return
+}
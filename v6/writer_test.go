@@ -0,0 +1,138 @@
+package v6
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// notebooks are the v6 files the tests read
+var notebooks = []string{
+	"migration_v6.rm",
+	"v6_text.rm",
+}
+
+func readNotebook(t *testing.T, name string) ([]byte, Scene) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "notebooks", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scene, err := ReadScene(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return data, scene
+}
+
+func TestWriteSceneRoundTrip(t *testing.T) {
+	for _, name := range notebooks {
+		for _, dirty := range []bool{false, true} {
+			data, scene := readNotebook(t, name)
+			if dirty {
+				// every item is encoded again instead of copied
+				for _, block := range scene.Blocks {
+					if block.Item != nil {
+						block.Item.Item().IsDirty = true
+					}
+				}
+			}
+			var written bytes.Buffer
+			if err := WriteScene(&written, &scene); err != nil {
+				t.Fatalf("%s dirty=%t: %v", name, dirty, err)
+			}
+			if !bytes.Equal(written.Bytes(), data) {
+				t.Errorf("%s dirty=%t: written file differs at byte %d", name, dirty, firstDifference(written.Bytes(), data))
+			}
+		}
+	}
+}
+
+func firstDifference(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// erased and pasted lines are written as new items and read back
+func TestEraseWriteRead(t *testing.T) {
+	for _, name := range notebooks {
+		_, scene := readNotebook(t, name)
+		scene.EraseArea(Rect{MinX: -100, MinY: -100, MaxX: 100, MaxY: 100})
+		if _, err := scene.Paste(0, scene.Copy(Rect{MinX: -1000, MinY: -1000, MaxX: 1000, MaxY: 1000}), 0, 500); err != nil {
+			t.Fatal(err)
+		}
+		var want []int
+		for _, layer := range scene.Layers {
+			want = append(want, pointCounts(layer.Lines)...)
+		}
+		var written bytes.Buffer
+		if err := WriteScene(&written, &scene); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		read, err := ReadScene(bytes.NewReader(written.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: written file can't be read: %v", name, err)
+		}
+		var got []int
+		for _, layer := range read.Layers {
+			got = append(got, pointCounts(layer.Lines)...)
+		}
+		if !equalCounts(got, want) {
+			t.Errorf("%s: points of the lines read back %v, want %v", name, got, want)
+		}
+	}
+}
+
+// lines pasted into a layer that has groups are linked to the lines of the
+// layer only, the groups are sequences of their own
+func TestPasteIntoGroups(t *testing.T) {
+	_, scene := readNotebook(t, "v6_text.rm")
+	layer := scene.Layers[0]
+	grouped := 0
+	for _, line := range layer.Lines {
+		if line.ParentId != layer.Id {
+			grouped++
+		}
+	}
+	if grouped == 0 {
+		t.Fatal("the notebook has no groups")
+	}
+	copied := scene.Copy(Rect{MinX: -1000, MinY: -1000, MaxX: 1000, MaxY: 1000})
+	pasted, err := scene.Paste(0, copied, 0, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written bytes.Buffer
+	if err := WriteScene(&written, &scene); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadScene(bytes.NewReader(written.Bytes()))
+	if err != nil {
+		t.Fatalf("written file can't be read: %v", err)
+	}
+
+	parents := make(map[CrdtId]CrdtId)
+	for _, line := range read.Layers[0].Lines {
+		parents[line.Id] = line.ParentId
+	}
+	for i, p := range pasted {
+		if parent, ok := parents[p.Id]; !ok || parent != layer.Id {
+			t.Fatalf("pasted line %v read back in %v, want %v", p.Id, parent, layer.Id)
+		}
+		for _, neighbour := range []CrdtId{p.Left, p.Right} {
+			if neighbour != CrdtId(0) && parents[neighbour] != layer.Id {
+				t.Errorf("pasted line %v is linked to %v of %v", p.Id, neighbour, parents[neighbour])
+			}
+		}
+		if i > 0 && p.Left != pasted[i-1].Id {
+			t.Errorf("pasted line %v follows %v, want %v", p.Id, p.Left, pasted[i-1].Id)
+		}
+	}
+	if first := pasted[0]; first.Left != CrdtId(0) {
+		t.Errorf("the first line of the layer follows %v", first.Left)
+	}
+}